@@ -0,0 +1,183 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"errors"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/go-cmd/cmd"
+)
+
+/* capability checks of probe_commands - each failed check carries a message & a hint for the user */
+
+const probe_timeout = 10 * time.Second
+
+/* minimum versions (older versions are reported as warning) */
+var probe_min_versions = map[string]string{
+	"mpv"        : "0.33.0",
+	"streamlink" : "5.0.0",
+}
+
+const ytdl_max_age = 60     // days - extractors break quickly w/ outdated yt-dlp
+
+type CapCheck struct {
+	Name       string    `json:"name"`
+	Ok         bool      `json:"ok"`
+	Level      string    `json:"level"`             // error: fnordstream won't work, warning: feature missing/degraded
+	Message    string    `json:"message"`
+	Hint       string    `json:"hint,omitempty"`    // what to do about it
+}
+
+var version_re = regexp.MustCompile(`\d+(\.\d+)+`)
+
+/* first version number in --version output (mpv v0.37.0-..., streamlink 6.7.2, 2024.03.10) */
+func parse_version(stdout string) string {
+	return version_re.FindString(stdout)
+}
+
+/* compare dotted versions: -1, 0, 1 */
+func version_cmp(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for idx := 0; (idx < len(as)) || (idx < len(bs)); idx++ {
+		var x, y int
+		if idx < len(as) { x, _ = strconv.Atoi(as[idx]) }
+		if idx < len(bs) { y, _ = strconv.Atoi(bs[idx]) }
+		if x != y {
+			if x < y { return -1 }
+			return 1
+		}
+	}
+	return 0
+}
+
+/* run command w/ timeout */
+func probe_run(command *CommandConfig, args ...string) cmd.Status {
+	ctx := command.new_cmd(cmd.Options{ Buffered : true }, args...)
+	select {
+		case status := <-ctx.Start():
+			return status
+		case <-time.After(probe_timeout):
+			ctx.Stop()
+			return cmd.Status{ Exit : -1, Error : fmt.Errorf("%s %s: timeout", command.Path, strings.Join(args, " ")) }
+	}
+}
+
+func probe_output_has(command *CommandConfig, needle string, args ...string) (bool, error) {
+	status := probe_run(command, args...)
+	if status.Error != nil { return false, status.Error }
+	for _, line := range append(status.Stdout, status.Stderr...) {
+		if strings.Contains(line, needle) { return true, nil }
+	}
+	return false, nil
+}
+
+func check_min_version(name string, version string) *CapCheck {
+	min, ok := probe_min_versions[name]
+	if !ok { return nil }
+	res := &CapCheck{ Name : name + "_version", Ok : true, Level : "warning" }
+	switch {
+		case version == "":
+			res.Ok, res.Message = false, "cannot determine " + name + " version"
+		case version_cmp(version, min) < 0:
+			res.Ok, res.Message = false, fmt.Sprintf("%s %s is older than %s", name, version, min)
+			res.Hint = "update " + name
+		default:
+			res.Message = fmt.Sprintf("%s %s >= %s", name, version, min)
+	}
+	return res
+}
+
+/* players need a display to open their windows (linux only - X11 or wayland) */
+func check_display(mpv *CommandConfig) *CapCheck {
+	if runtime.GOOS != "linux" { return nil }
+	res := &CapCheck{ Name : "display", Ok : true, Level : "error" }
+	for _, name := range []string{"WAYLAND_DISPLAY", "DISPLAY"} {
+		val, ok := mpv.Env[name]
+		if !ok { val = os.Getenv(name) }
+		if val != "" {
+			res.Message = name + "=" + val
+			return res
+		}
+	}
+	res.Ok      = false
+	res.Message = "neither DISPLAY nor WAYLAND_DISPLAY is set - players cannot open windows"
+	res.Hint    = "set DISPLAY (e.g. :0) in the environment of fnordstream or in players.mpv.env of the config file"
+	return res
+}
+
+func mpv_checks(mpv *CommandConfig, info *CmdInfo) []*CapCheck {
+	res := []*CapCheck{ check_min_version("mpv", info.Version) }
+
+	ipc := &CapCheck{ Name : "mpv_ipc", Level : "error", Message : "JSON IPC supported" }
+	var err error
+	if ipc.Ok, err = probe_output_has(mpv, "input-ipc-server", "--list-options"); !ipc.Ok {
+		ipc.Message = "mpv has no JSON IPC support (--input-ipc-server) - players cannot be controlled"
+		if err != nil { ipc.Message = "cannot list mpv options: " + err.Error() }
+		ipc.Hint    = "install an mpv build with IPC support"
+	}
+	res = append(res, ipc)
+
+	/* mpv --hwdec=help lists the hwdec methods of this build (besides no/auto*) */
+	hwdec   := &CapCheck{ Name : "hwdec", Level : "warning" }
+	status  := probe_run(mpv, "--hwdec=help")
+	err      = status.Error
+	methods := []string{}
+	for _, line := range status.Stdout {
+		if !strings.HasPrefix(line, " ") { continue }
+		fields := strings.Fields(line)
+		if (len(fields) < 1) || (fields[0] == "no") || (fields[0] == "yes") || strings.HasPrefix(fields[0], "auto") { continue }
+		methods = append(methods, fields[0])
+	}
+	hwdec.Ok      = len(methods) > 0
+	hwdec.Message = "hardware decoding: " + strings.Join(methods, ", ")
+	if !hwdec.Ok {
+		hwdec.Message = "no hardware decoding available - expect high CPU load with several streams"
+		if err != nil { hwdec.Message = "cannot list hwdec methods: " + err.Error() }
+		hwdec.Hint    = "install GPU video drivers (e.g. VA-API/VDPAU) or use an mpv build with hwdec support"
+	}
+	res = append(res, hwdec)
+	return append(res, check_display(mpv))
+}
+
+/* yt-dlp versions are release dates (YYYY.MM.DD[.build]) */
+func ytdl_checks(ytdl *CommandConfig, info *CmdInfo) []*CapCheck {
+	res   := &CapCheck{ Name : "ytdl_age", Level : "warning" }
+	parts := strings.Split(info.Version, ".")
+	date  := time.Time{}
+	err   := errors.New("no release date")
+	if len(parts) >= 3 { date, err = time.Parse("2006.01.02", strings.Join(parts[:3], ".")) }
+	if err != nil {
+		res.Message = "cannot determine yt-dlp release date"
+		res.Hint    = "update yt-dlp (yt-dlp -U)"
+		return []*CapCheck{ res }
+	}
+	age        := int(time.Since(date).Hours() / 24)
+	res.Ok      = age <= ytdl_max_age
+	res.Message = fmt.Sprintf("yt-dlp %s is %d days old", info.Version, age)
+	if !res.Ok { res.Hint = "update yt-dlp (yt-dlp -U) - outdated versions often fail to play streams" }
+	return []*CapCheck{ res }
+}
+
+func streamlink_checks(streamlink *CommandConfig, info *CmdInfo) []*CapCheck {
+	res := []*CapCheck{ check_min_version("streamlink", info.Version) }
+	ads := &CapCheck{ Name : "twitch_disable_ads", Level : "warning", Message : "--twitch-disable-ads supported" }
+	var err error
+	if ads.Ok, err = probe_output_has(streamlink, "--twitch-disable-ads", "--help"); !ads.Ok {
+		ads.Message = "streamlink does not support --twitch-disable-ads"
+		if err != nil { ads.Message = "cannot list streamlink options: " + err.Error() }
+		ads.Hint    = "do not use the twitch-disable-ads option with this streamlink version"
+	}
+	return append(res, ads)
+}
+
+/* capability checks per command - run if --version succeeded */
+var probe_checks = map[string]func(command *CommandConfig, info *CmdInfo) []*CapCheck{
+	"mpv"        : mpv_checks,
+	"yt-dlp"     : ytdl_checks,
+	"streamlink" : streamlink_checks,
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"time"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		stdout      string
+		version     string
+	}{
+		{ "mpv v0.37.0-434-g1e7f6a0 Copyright © 2000-2023 mpv/MPlayer/mplayer2 projects", "0.37.0" },
+		{ "mpv 0.34.1 Copyright © 2000-2021 mpv/MPlayer/mplayer2 projects",                "0.34.1" },
+		{ "streamlink 6.7.2\n",                                                           "6.7.2" },
+		{ "2024.03.10\n",                                                                 "2024.03.10" },
+		{ "2024.03.10.232701\n",                                                          "2024.03.10.232701" },
+		{ "xrandr program version       1.5.2\nServer reports RandR version 1.6",         "1.5.2" },
+		{ "version 7",                                                                    "" },
+		{ "",                                                                             "" },
+	}
+	for _, test := range tests {
+		if version := parse_version(test.stdout); version != test.version {
+			t.Errorf("parse_version(%q) = %q, want %q", test.stdout, version, test.version)
+		}
+	}
+}
+
+func TestVersionCmp(t *testing.T) {
+	tests := []struct {
+		a, b        string
+		res         int
+	}{
+		{ "0.33.0",     "0.33.0",  0 },
+		{ "0.37.0",     "0.33.0",  1 },
+		{ "0.32.9",     "0.33.0", -1 },
+		{ "0.10.0",     "0.9.0",   1 },     // numeric, not lexical
+		{ "1.0",        "1.0.0",   0 },     // missing parts are 0
+		{ "1.0.1",      "1.0",     1 },
+		{ "5",          "5.0.0",   0 },
+		{ "4.99.99",    "5.0.0",  -1 },
+		{ "2024.03.10", "2023.12.31", 1 },
+		{ "",           "0.0",     0 },
+	}
+	for _, test := range tests {
+		if res := version_cmp(test.a, test.b); res != test.res {
+			t.Errorf("version_cmp(%q, %q) = %d, want %d", test.a, test.b, res, test.res)
+		}
+		if res := version_cmp(test.b, test.a); res != -test.res {
+			t.Errorf("version_cmp(%q, %q) = %d, want %d", test.b, test.a, res, -test.res)
+		}
+	}
+}
+
+func TestCheckMinVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		ok          bool
+	}{
+		{ "mpv",        "0.37.0", true },
+		{ "mpv",        "0.33.0", true },
+		{ "mpv",        "0.32.0", false },
+		{ "mpv",        "",       false },
+		{ "streamlink", "6.7.2",  true },
+		{ "streamlink", "4.3.0",  false },
+	}
+	for _, test := range tests {
+		check := check_min_version(test.name, test.version)
+		if (check == nil) || (check.Ok != test.ok) {
+			t.Errorf("check_min_version(%q, %q) = %+v, want ok %v", test.name, test.version, check, test.ok)
+		}
+	}
+	if check := check_min_version("yt-dlp", "2024.03.10"); check != nil {
+		t.Errorf("check_min_version(yt-dlp): no check expected, got %+v", check)
+	}
+}
+
+func TestYtdlAge(t *testing.T) {
+	recent := time.Now().AddDate(0, 0, -10).Format("2006.01.02")
+	tests := []struct {
+		version     string
+		ok          bool
+	}{
+		{ recent,               true },
+		{ recent + ".123456",   true },     // nightly build
+		{ "2020.01.01",         false },
+		{ "2024.13.40",         false },    // no valid date
+		{ "",                   false },
+	}
+	for _, test := range tests {
+		checks := ytdl_checks(&CommandConfig{ Path : "yt-dlp" }, &CmdInfo{ Version : test.version })
+		if (len(checks) != 1) || (checks[0].Ok != test.ok) {
+			t.Errorf("ytdl_checks(%q) = %+v, want ok %v", test.version, checks[0], test.ok)
+		}
+	}
+}
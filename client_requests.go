@@ -2,11 +2,14 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"math"
+	"errors"
+	"time"
 	"regexp"
 	"strconv"
+	"strings"
 	"runtime"
+	"log/slog"
 	//"runtime/debug"
 	"encoding/json"
 	"github.com/mitchellh/mapstructure"
@@ -30,11 +33,14 @@ var version_info = func() string {
 
 type RequestHandler func(*StreamHub, *Client, map[string]interface {})
 
+/* allowed characters in stream locations - others are removed */
+var location_re = regexp.MustCompile(`[^a-zA-Z0-9-_/:.,?&@=#%]`)
+
 func set_displays(hub *StreamHub, client *Client, request map[string]interface {}) {
 	displays := []Display{}
 	err := mapstructure.Decode(request["displays"], &displays)
 	if err != nil {
-		fmt.Println(err)
+		hub.log.Warn("set_displays: invalid displays", "err", err)
 		return 
 	}
 	hub.displays = displays
@@ -42,13 +48,14 @@ func set_displays(hub *StreamHub, client *Client, request map[string]interface {
 }
 
 func get_displays(hub *StreamHub, client *Client, request map[string]interface {}) {
-	send_response(hub.notifications, client, "displays", hub.displays)
+	hub.send_response(client, "displays", hub.displays)
 }
 
 func detect_displays(hub *StreamHub, client *Client, request map[string]interface {}) {
-	send := hub.notifications
+	send   := hub.notifications
+	xrandr := hub.config.command("xrandr")
 	go func(){
-		send_response(send, client, "displays", displays_detect())
+		send_response(send, client, "displays", displays_detect(xrandr))
 	}()
 }
 
@@ -64,7 +71,8 @@ func auto_layout(displays []Display, n_streams int) []Viewport {
 	/* no usable display found? */
 	if (n_displays < 1) { return viewports }
 
-	fmt.Printf("n_displays: %d, n_streams: %d\n", n_displays, n_streams)
+	log := logger("hub")
+	log.Debug("auto_layout", "n_displays", n_displays, "n_streams", n_streams)
 
 	viewport_id := 0
 	/* iterate over displays again, allocate viewports */
@@ -79,8 +87,9 @@ func auto_layout(displays []Display, n_streams int) []Viewport {
 		if n_streams < disp_streams { disp_streams = n_streams }  // clamp number of viewports to n_streams
 		w_step, h_step := w/grid, h/grid
 
-		fmt.Printf("   display[%d] (%s) resolution: %dx%d\n",disp_idx,display.Name,w,h)
-		fmt.Printf("   disp_streams: %d - using %dx%d grid (%dx%d)\n",disp_streams,grid,grid,w_step,h_step)
+		log.Debug("auto_layout display", "display", disp_idx, "name", display.Name,
+			"resolution", fmt.Sprintf("%dx%d",w,h), "disp_streams", disp_streams,
+			"grid", fmt.Sprintf("%dx%d",grid,grid), "viewport", fmt.Sprintf("%dx%d",w_step,h_step))
 
 		center_ofs := 0
 		/* allocate viewports for current display */
@@ -124,7 +133,7 @@ func suggest_viewports(hub *StreamHub, client *Client, request map[string]interf
 	 * if no displays are provided hub.displays are used */
 	displays := []Display{}
 	err := mapstructure.Decode(request["displays"], &displays)
-	fmt.Println("displays provided:",err==nil);
+	hub.log.Debug("suggest_viewports", "displays_provided", err==nil)
 	if (err != nil) || (len(displays)<1) {
 		displays = hub.displays
 	}
@@ -134,34 +143,30 @@ func suggest_viewports(hub *StreamHub, client *Client, request map[string]interf
 
 	viewports := auto_layout(displays, n_streams)
 	if !discard { hub.viewports = viewports }
-	send      := hub.notifications
-	send_response(send, client, "viewports", viewports)
+	hub.send_response(client, "viewports", viewports)
 }
 
 /* start playing all streams */
 func start_streams(hub *StreamHub, client *Client, request map[string]interface {}) {
 
-	if hub.streams_playing { return }
-
-	// sanitize location
-	re := regexp.MustCompile(`[^a-zA-Z0-9-_/:.,?&@=#%]`)
+	if hub.streams_playing || hub.shutting_down { return }
 
-	/* check & adopt stream list */
+	/* check & adopt stream list - entries are locations or playlists */
 	streamlist, ok := request["streams"].([]interface{})
 	if !ok { return }
 	locations := []string{}
-	for _, loc := range streamlist {
-		location, ok := loc.(string)
+	playlists := []*PlaylistConfig{}
+	for _, entry := range streamlist {
+		playlist, ok := parse_stream_entry(entry, location_re)
 		if !ok { return }
-		location  = re.ReplaceAllString(location, "")
-		if len(location) < 1 { return }
-		locations = append(locations, location)
+		playlists = append(playlists, playlist)
+		locations = append(locations, playlist.Locations[0])
 	}
 
 	/* check & adopt viewports */
 	viewports := []Viewport{}
 	mapstructure.Decode(request["viewports"], &viewports)
-	fmt.Println("start_streams n_viewports:", len(viewports))
+	hub.log.Debug("start_streams", "n_viewports", len(viewports))
 	if len(viewports) < len(locations) {
 		viewports     = hub.viewports
 	}
@@ -173,18 +178,43 @@ func start_streams(hub *StreamHub, client *Client, request map[string]interface
 		return
 	}
 
-	/* check & adopt options */
+	/* check & adopt options - defaults from config */
 	options := map[string]bool{}
+	for k, v := range hub.config.Playback_options { options[k] = v }
 	//fmt.Println(request["options"])
 	mapstructure.Decode(request["options"], &options)
 
+	/* optional watchdog settings - defaults from hub */
+	watchdog := hub.watchdog
+	mapstructure.Decode(request["watchdog"], &watchdog)
+
+	/* optional snapshot interval (seconds) */
+	thumbnail_interval := hub.thumbnail_interval
+	if secs, ok := request["thumbnail_interval"].(float64); ok && (secs >= 0) {
+		thumbnail_interval = time.Duration(secs * float64(time.Second))
+	}
+
 	hub.streams_playing   = true
 	hub.stream_locations  = locations
 	hub.viewports         = viewports
 	hub.playback_options  = options
 
-	hub.streams           = make([]*Stream,       len(hub.stream_locations))
-	hub.stream_status     = make([]*StreamStatus, len(hub.stream_locations))
+	hub.pipe_gen++
+	hub.streams           = make([]*Stream,        len(hub.stream_locations))
+	hub.stream_status     = make([]*StreamStatus,  len(hub.stream_locations))
+	hub.stream_logs       = make([]*PlayerLog,     len(hub.stream_locations))
+	hub.stream_metrics    = make([]*StreamMetrics, len(hub.stream_locations))
+	hub.prop_subs         = make([]map[string]map[*Client]bool, len(hub.stream_locations))
+
+	/* configured yt-dlp for mpv's ytdl hook */
+	ytdl_path := ""
+	if ytdl := hub.config.command("yt-dlp"); ytdl.Path != "yt-dlp" {
+		ytdl_path = ytdl.arg_path()
+		if strings.Contains(ytdl_path, ",") {      // comma separates script-opts
+			hub.log.Warn("yt-dlp path contains a comma - using mpv default", "path", ytdl_path)
+			ytdl_path = ""
+		}
+	}
 
 	/* create streams */
 	for idx, location := range hub.stream_locations {
@@ -193,14 +223,20 @@ func start_streams(hub *StreamHub, client *Client, request map[string]interface
 		mpv_args := []string{
 			"--mute=yes",
 			"--border=no",
-			"--really-quiet",
+			"--quiet",
+			"--msg-level=all=warn",    // keep warnings/errors for the player log
 			"--geometry=" + viewport.String(),
 		}
+		if ytdl_path != "" {
+			mpv_args = append(mpv_args, "--script-opts=ytdl_hook-ytdl_path=" + ytdl_path)
+		}
+		mpv_args = append(mpv_args, hub.config.Mpv_args...)
 		streamlink_args := []string{
-			"--player=mpv",
+			"--player=" + hub.config.command("mpv").arg_path(),
 			"--player-fifo",
 			//"-v", // verbose player
 		}
+		streamlink_args = append(streamlink_args, hub.config.Streamlink_args...)
 
 		if !options["start_muted"] {
 			mpv_args[0] = "--mute=no"
@@ -208,9 +244,20 @@ func start_streams(hub *StreamHub, client *Client, request map[string]interface
 
 		config := &PlayerConfig{
 			mpv_args            : mpv_args,
+			mpv_cmd             : hub.config.command("mpv"),
+			streamlink_cmd      : hub.config.command("streamlink"),
+			ytdl_cmd            : hub.config.command("yt-dlp"),
 			location            : location,
-			ipc_pipe            : hub.pipe_prefix + strconv.Itoa(idx),
+			playlist            : *playlists[idx],
+			ipc_pipe            : hub.pipe_prefix + strconv.Itoa(hub.pipe_gen) + "_" + strconv.Itoa(idx),
 			restart_error_delay : -1,
+			watchdog            : watchdog,
+			record_dir          : hub.record_dir,
+			record_template     : hub.record_template,
+		}
+		if hub.thumbnail_dir != "" {
+			config.thumbnail_file     = thumbnail_file(hub.thumbnail_dir, idx)
+			config.thumbnail_interval = thumbnail_interval
 		}
 
 		if options["restart_error"] {
@@ -232,6 +279,14 @@ func start_streams(hub *StreamHub, client *Client, request map[string]interface
 			Location      : location,
 			Viewport_id   : viewport.Id,
 		}
+		if len(playlists[idx].Locations) > 1 {
+			hub.stream_status[idx].Playlist = playlists[idx].Locations
+		}
+		hub.stream_status[idx].Backups = playlists[idx].Backups
+		hub.stream_status[idx].Title   = playlists[idx].Title
+		hub.stream_logs[idx]    = NewPlayerLog(player_log_lines)
+		hub.stream_metrics[idx] = &StreamMetrics{}
+		hub.prop_subs[idx]      = make(map[string]map[*Client]bool)
 	} // foreach stream
 
 	/* signal playing mode to all clients before starting the streams
@@ -242,6 +297,7 @@ func start_streams(hub *StreamHub, client *Client, request map[string]interface
 	for _, stream := range hub.streams {
 		stream.Play()
 	}
+	if hub.resume_session { hub.session_save(request) }
 }
 
 /* stop playing completely */
@@ -255,14 +311,19 @@ func stop_streams(hub *StreamHub, client *Client, request map[string]interface {
 		hub.streams[idx]       = nil
 		hub.stream_status[idx] = nil
 	}
-	hub.streams       = nil
-	hub.stream_status = nil
+	hub.thumbnails_remove()      // don't serve snapshots of this set to the next one
+	hub.streams        = nil
+	hub.stream_status  = nil
+	hub.stream_logs    = nil
+	hub.stream_metrics = nil
+	hub.prop_subs      = nil
 
 	hub.streams_playing   = false
 	global_status(hub, nil, nil) /* signal global stopped mode to all clients */
+	if hub.resume_session && !hub.shutting_down { hub.session_clear() }    // keep session for restart
 }
 
-func global_status(hub *StreamHub, client *Client, request map[string]interface {}) {
+func (hub *StreamHub) global_status_payload() map[string]interface{} {
 	note := map[string]interface{}{
 		"os"      : runtime.GOOS,
 		"version" : version_info,
@@ -271,7 +332,12 @@ func global_status(hub *StreamHub, client *Client, request map[string]interface
 	if hub.streams_playing {
 		note["streams"] = hub.stream_status
 	}
-	send_response(hub.notifications, client, "global_status", &note)
+	return note
+}
+
+func global_status(hub *StreamHub, client *Client, request map[string]interface {}) {
+	note := hub.global_status_payload()
+	hub.send_response(client, "global_status", &note)
 }
 
 func lookup_stream(hub *StreamHub, request map[string]interface {}) (res *Stream, bulk_sel bool) {
@@ -300,28 +366,28 @@ func lookup_stream(hub *StreamHub, request map[string]interface {}) (res *Stream
 }
 
 func stream_ctl(hub *StreamHub, client *Client, request map[string]interface {}) {
-	var allowed_ctls = map[string]bool{
-		"volume" : true,
-		"seek"   : true,
-		"mute"   : true,
-		"play"   : true,
-	}
-
 	ctl, ok := request["ctl"].(string)
 	if !ok { return }
 
-	value, ok := request["value"]
-	if !ok { return }
-
-	allowed, ok := allowed_ctls[ctl]
-	if (!ok) || (!allowed) { return }
+	value := request["value"]
 
-	// sanitize val
-	re  := regexp.MustCompile(`[^a-zA-Z0-9]`)
-	val := re.ReplaceAllString(fmt.Sprint(value),"")
+	handler, ok := stream_ctls[ctl]
+	if !ok {
+		hub.log.Warn("stream_ctl: unknown control", "ctl", ctl)
+		return
+	}
 
-	msg := &StreamCtl{cmd:ctl, val:val}
+	// validate value and build control command
+	msg, err := handler(value, request)
+	if err != nil {
+		hub.log.Warn("stream_ctl: invalid value", "ctl", ctl, "err", err)
+		return
+	}
+	stream_ctl_send(hub, request, msg)
+}
 
+/* send control to stream(s) selected by stream_id of request */
+func stream_ctl_send(hub *StreamHub, request map[string]interface {}, msg *StreamCtl) {
 	stream, bulk_sel := lookup_stream(hub, request)
 	if bulk_sel {
 		for _, s := range hub.streams {         // issue to multiple or all streams
@@ -332,29 +398,278 @@ func stream_ctl(hub *StreamHub, client *Client, request map[string]interface {})
 	}
 }
 
-func get_profiles(hub *StreamHub, client *Client, request map[string]interface {}) {
-	send_response(hub.notifications, client, "profiles", hub.stream_profiles)
+/* recent player output lines of a single stream */
+func get_stream_log(hub *StreamHub, client *Client, request map[string]interface {}) {
+	sid_f, ok := request["stream_id"].(float64)
+	if !ok { return }
+	stream_id := int(sid_f)
+	if (stream_id < 0) || (stream_id >= len(hub.stream_logs)) { return }
+
+	res := map[string]interface{}{
+		"stream_id" : stream_id,
+		"lines"     : hub.stream_logs[stream_id].Lines(),
+	}
+	hub.send_response(client, "stream_log", res)
 }
 
-func save_profile(hub *StreamHub, client *Client, request map[string]interface {}) {
-	name, ok := request["profile_name"].(string)
+/* (un)subscribe extra mpv properties for a stream
+ * updates are sent as (rate limited) player_property notifications to subscribers only */
+func subscribe_properties(hub *StreamHub, client *Client, request map[string]interface {}) {
+	if client == nil { return }
+	sid_f, ok := request["stream_id"].(float64)
 	if !ok { return }
+	stream_id := int(sid_f)
+	if (stream_id < 0) || (stream_id >= len(hub.prop_subs)) { return }
 
-	profile, ok := request["profile"].(interface{})
+	props, ok := request["properties"].([]interface{})
 	if !ok { return }
 
+	subscribe := request["request"] == "subscribe_properties"
+	for _, p := range props {
+		name, ok := p.(string)
+		if !ok { continue }
+		if !subscribe {
+			hub.property_unsubscribe(client, stream_id, name)
+		} else if !hub.property_subscribe(client, stream_id, name) {
+			hub.log.Warn("subscribe_properties: rejected", "stream_id", stream_id, "property", name)
+		}
+	}
+
+	res := map[string]interface{}{
+		"stream_id"  : stream_id,
+		"properties" : hub.property_subscriptions(client, stream_id),
+	}
+	hub.send_response(client, "property_subscriptions", res)
+}
+
+/* start/stop recording of stream(s) */
+func record_ctl(hub *StreamHub, client *Client, request map[string]interface {}) {
+	val := "no"
+	if request["request"] == "record_start" { val = "yes" }
+	stream_ctl_send(hub, request, &StreamCtl{cmd:"record", val:val})
+}
+
+/* build start_streams request from a stream profile */
+func profile_start_request(profile *Profile) map[string]interface{} {
+	if (profile == nil) || (len(profile.Stream_locations) < 1) { return nil }
+
+	options := map[string]interface{}{  // same defaults as console mode
+		"start_muted"   : true,
+		"restart_error" : true,
+	}
+	for k, v := range profile.Options { options[k] = v }
+	return map[string]interface{}{
+		"request"   : "start_streams",
+		"streams"   : profile.Stream_locations,
+		"viewports" : profile.Viewports,
+		"options"   : options,
+	}
+}
+
+/* stop running streams & start streams of a stored profile */
+func (hub *StreamHub) profile_start(name string) error {
+	profile, ok := hub.stream_profiles[name]
+	if !ok { return errors.New("profile not found") }
+	request := profile_start_request(profile)
+	if request == nil { return errors.New("profile has no streams") }
+	stop_streams(hub, nil, nil)          // switch stream sets
+	start_streams(hub, nil, request)
+	if !hub.streams_playing { return errors.New("start_streams failed") }
+	return nil
+}
+
+func start_profile(hub *StreamHub, client *Client, request map[string]interface {}) {
+	name, _ := request["profile_name"].(string)
+	if err := hub.profile_start(name); err != nil {
+		profile_failed(hub, client, name, err)
+	}
+}
+
+func profile_failed(hub *StreamHub, client *Client, name string, err error) {
+	hub.log.Warn("profile request failed", "profile", name, "err", err)
+	res := map[string]interface{}{
+		"profile_name" : name,
+		"error"        : err.Error(),
+	}
+	hub.send_response(client, "profile_failed", res)
+}
+
+/* store profile, record revision & announce new profile list to all clients */
+func (hub *StreamHub) profile_store(client *Client, name string, action string, profile *Profile) {
+	old, existed := hub.stream_profiles[name]
 	hub.stream_profiles[name] = profile
-	send_response(hub.notifications, nil, "profiles", hub.stream_profiles)
-	save_json("stream_profiles.json", hub.stream_profiles)
+	if err := profiles_save(hub.stream_profiles); err != nil {
+		if existed {             // keep profiles in sync w/ the file, no revision for unsaved changes
+			hub.stream_profiles[name] = old
+		} else {
+			delete(hub.stream_profiles, name)
+		}
+		profile_failed(hub, client, name, err)
+	} else {
+		hub.profile_history_add(client, name, action, old, profile)
+	}
+	hub.send_response(nil, "profiles", hub.stream_profiles)
+}
+
+func get_profiles(hub *StreamHub, client *Client, request map[string]interface {}) {
+	hub.send_response(client, "profiles", hub.stream_profiles)
+}
+
+func save_profile(hub *StreamHub, client *Client, request map[string]interface {}) {
+	name, _ := request["profile_name"].(string)
+	if err := profile_name_check(name); err != nil {
+		profile_failed(hub, client, name, err)
+		return
+	}
+	profile, err := profile_decode(request["profile"])
+	if err != nil {
+		profile_failed(hub, client, name, err)
+		return
+	}
+	hub.profile_store(client, name, "save", profile)
 }
 
 func delete_profile(hub *StreamHub, client *Client, request map[string]interface {}) {
 	name, ok := request["profile_name"].(string)
 	if !ok { return }
+	old, ok := hub.stream_profiles[name]
+	if !ok { return }
 
 	delete(hub.stream_profiles, name)
-	send_response(hub.notifications, nil, "profiles", hub.stream_profiles)
-	save_json("stream_profiles.json", hub.stream_profiles)
+	if err := profiles_save(hub.stream_profiles); err != nil {
+		hub.stream_profiles[name] = old       // still in the file
+		profile_failed(hub, client, name, err)
+	} else {
+		hub.profile_history_add(client, name, "delete", old, nil)
+	}
+	hub.send_response(nil, "profiles", hub.stream_profiles)
+}
+
+/* single profile as JSON document (ProfileExport) - saved as file by the client */
+func profile_export(hub *StreamHub, client *Client, request map[string]interface {}) {
+	name, _ := request["profile_name"].(string)
+	profile, ok := hub.stream_profiles[name]
+	if !ok {
+		profile_failed(hub, client, name, errors.New("profile not found"))
+		return
+	}
+	res := &ProfileExport{
+		Format  : profile_export_format,
+		Name    : name,
+		Profile : profile,
+	}
+	hub.send_response(client, "profile_export", res)
+}
+
+/* import ProfileExport document from "data"
+ * optional: profile_name (store under different name), overwrite (replace existing profile) */
+func profile_import(hub *StreamHub, client *Client, request map[string]interface {}) {
+	data, ok := request["data"].(map[string]interface{})
+	if !ok {
+		profile_failed(hub, client, "", errors.New("no profile data"))
+		return
+	}
+	if format, _ := data["format"].(string); format != profile_export_format {
+		profile_failed(hub, client, "", fmt.Errorf("unsupported format %q", format))
+		return
+	}
+	name, _ := data["name"].(string)
+	if override, ok := request["profile_name"].(string); ok && (override != "") {
+		name = override
+	}
+	if err := profile_name_check(name); err != nil {
+		profile_failed(hub, client, name, err)
+		return
+	}
+	overwrite, _ := request["overwrite"].(bool)
+	if _, exists := hub.stream_profiles[name]; exists && !overwrite {
+		profile_failed(hub, client, name, errors.New("profile exists"))
+		return
+	}
+	profile, err := profile_decode(data["profile"])
+	if err != nil {
+		profile_failed(hub, client, name, err)
+		return
+	}
+	hub.log.Info("profile imported", "profile", name)
+	hub.profile_store(client, name, "import", profile)
+}
+
+/* revisions of a profile (oldest first) - also available for deleted profiles */
+func profile_history(hub *StreamHub, client *Client, request map[string]interface {}) {
+	name, _ := request["profile_name"].(string)
+	history, ok := hub.profile_history[name]
+	if !ok { history = []*ProfileRevision{} }
+	res := map[string]interface{}{
+		"profile_name" : name,
+		"revisions"    : history,
+	}
+	hub.send_response(client, "profile_history", res)
+}
+
+/* roll back profile to an earlier revision (restores deleted profiles as well) */
+func profile_restore(hub *StreamHub, client *Client, request map[string]interface {}) {
+	name, _ := request["profile_name"].(string)
+	rev, ok := request["rev"].(float64)
+	if !ok {
+		profile_failed(hub, client, name, errors.New("no revision given"))
+		return
+	}
+	revision := hub.profile_revision(name, int(rev))
+	if (revision == nil) || (revision.Profile == nil) {
+		profile_failed(hub, client, name, fmt.Errorf("no such revision %v", rev))
+		return
+	}
+	hub.log.Info("profile restored", "profile", name, "rev", revision.Rev)
+	hub.profile_store(client, name, "restore", revision.Profile)
+	profile_history(hub, client, request)
+}
+
+func schedule_add(hub *StreamHub, client *Client, request map[string]interface {}) {
+	entry := &ScheduleEntry{}
+	mapstructure.Decode(request["entry"], entry)
+
+	/* one-off timestamp: RFC3339 string or unix time */
+	raw, _ := request["entry"].(map[string]interface{})
+	switch at := raw["at"].(type) {
+		case string:
+			ts, err := time.Parse(time.RFC3339, at)
+			if err != nil {
+				hub.send_response(client, "schedule_failed", map[string]interface{}{"error" : err.Error()})
+				return
+			}
+			entry.At = &ts
+		case float64:
+			ts      := time.Unix(int64(at), 0)
+			entry.At = &ts
+	}
+
+	if err := hub.schedule_add(entry); err != nil {
+		hub.send_response(client, "schedule_failed", map[string]interface{}{"error" : err.Error()})
+		return
+	}
+	schedule_list(hub, nil, nil)
+}
+
+func schedule_list(hub *StreamHub, client *Client, request map[string]interface {}) {
+	hub.send_response(client, "schedule", hub.schedule_list())
+}
+
+func schedule_delete(hub *StreamHub, client *Client, request map[string]interface {}) {
+	id, ok := request["id"].(string)
+	if !ok || !hub.schedule_delete(id) { return }
+	schedule_list(hub, nil, nil)
+}
+
+/* convert M3U/PLS playlist (text in "data") to a stream list for start_streams/profiles */
+func import_playlist(hub *StreamHub, client *Client, request map[string]interface {}) {
+	data, _ := request["data"].(string)
+	streams, err := import_playlist_text(data)
+	if err != nil {
+		hub.send_response(client, "import_failed", map[string]interface{}{"error" : err.Error()})
+		return
+	}
+	hub.send_response(client, "imported_playlist", map[string]interface{}{"streams" : streams})
 }
 
 func probe_commands(hub *StreamHub, client *Client, request map[string]interface {}) {
@@ -366,23 +681,63 @@ func probe_commands(hub *StreamHub, client *Client, request map[string]interface
 	if runtime.GOOS == "linux" {
 		cmd_info["xrandr"] = nil
 	}
+	config := hub.config      // replaced (not modified) on reload
+	send   := hub.notifications
 	go func(){
 		for cmd, _ := range cmd_info {
-			cmd_info[cmd] = probe_command(cmd)
+			cmd_info[cmd] = probe_command(cmd, config.command(cmd))
 		}
-		send_response(hub.notifications, client, "probe_commands", cmd_info)
+		send_response(send, client, "probe_commands", cmd_info)
 	}()
 }
 
+/* change log level and/or stream state machine debug output at runtime */
+func log_ctl(hub *StreamHub, client *Client, request map[string]interface {}) {
+	if level_s, ok := request["level"].(string); ok {
+		level, err := parse_log_level(level_s)
+		if err != nil {
+			hub.log.Warn("log_ctl: invalid level", "level", level_s)
+			res := map[string]interface{}{
+				"level" : level_s,
+				"error" : err.Error(),
+			}
+			hub.send_response(client, "log_ctl_failed", res)
+			return
+		}
+		log_level.Set(level)
+	}
+	if debug, ok := request["stream_debug"].(bool); ok {
+		stream_debug.Store(debug)
+	}
+	hub.log.Info("log settings changed", "level", log_level.Level(), "stream_debug", stream_debug.Load())
+	res := map[string]interface{}{
+		"level"        : log_level.Level().String(),
+		"stream_debug" : stream_debug.Load(),
+	}
+	hub.send_response(client, "log_ctl", res)
+}
+
 /* handlers are executed in StreamHub.Run() context
  * may access & modify StreamHub XOR start gogoutines as needed */
 var req_handlers = map[string]RequestHandler{
 	"global_status"      : global_status,
 	"probe_commands"     : probe_commands,
+	"log_ctl"            : log_ctl,
+	"reload_config"      : reload_config,
 
 	"get_profiles"       : get_profiles,
 	"profile_save"       : save_profile,
 	"profile_delete"     : delete_profile,
+	"profile_export"     : profile_export,
+	"profile_import"     : profile_import,
+	"profile_history"    : profile_history,
+	"profile_start"      : start_profile,
+	"profile_restore"    : profile_restore,
+	"import_playlist"    : import_playlist,
+
+	"schedule_add"       : schedule_add,
+	"schedule_list"      : schedule_list,
+	"schedule_delete"    : schedule_delete,
 
 	"detect_displays"    : detect_displays,
 	"get_displays"       : get_displays,
@@ -394,6 +749,12 @@ var req_handlers = map[string]RequestHandler{
 	"stop_streams"       : stop_streams,
 
 	"stream_ctl"         : stream_ctl,
+	"get_stream_log"     : get_stream_log,
+	"record_start"       : record_ctl,
+	"record_stop"        : record_ctl,
+
+	"subscribe_properties"   : subscribe_properties,
+	"unsubscribe_properties" : subscribe_properties,
 }
 
 func client_request(hub *StreamHub, req *ClientRequest) {
@@ -407,33 +768,46 @@ func client_request(hub *StreamHub, req *ClientRequest) {
 	handler, ok := req_handlers[request]
 	//fmt.Println("req:", request, req, ok)
 	if !ok {
-		fmt.Println("client_request: handler missing: ",request)
+		hub.log.Warn("client_request: handler missing", "request", request)
 		return
 	}
 	handler(hub, client, msg)
 }
 
-/* helper function for sending a response to a client */
-func send_response(send chan<- *Notification, client *Client, request string, payload interface{}) {
+/* notification as sent to clients (w/o stream_id) */
+func note_json(notification string, payload interface{}) ([]byte, error) {
 	response := map[string]interface{} {
-		"notification"  : request,
+		"notification"  : notification,
 		"payload"       : payload,
 	}
-	json_response, err := json.Marshal(response)
+	return json.Marshal(response)
+}
+
+func new_response(client *Client, request string, payload interface{}) *Notification {
+	json_response, err := note_json(request, payload)
 	if err != nil {
-		log.Println("send_response JSON Marshal error:", err)
-		return
+		slog.Error("send_response JSON Marshal error", "err", err)
+		return nil
 	}
-	note := &Notification{
+	return &Notification{
 		dst           : client,
 		stream_id     : -1,
 		notification  : request,
 		payload       : payload,
 		json_message  : json_response,
 	}
-	select {
-		case send <- note:
-		default:
-			log.Fatal("send_response: channel full!")
+}
+
+/* helper function for sending a response to a client - go routines only (may block) */
+func send_response(send chan<- *Notification, client *Client, request string, payload interface{}) {
+	if note := new_response(client, request, payload); note != nil {
+		send <- note
+	}
+}
+
+/* response from StreamHub.Run() context - queued in order, never blocks (see Run()) */
+func (hub *StreamHub) send_response(client *Client, request string, payload interface{}) {
+	if note := new_response(client, request, payload); note != nil {
+		hub.notify_queue = append(hub.notify_queue, note)
 	}
 }
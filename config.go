@@ -0,0 +1,218 @@
+package main
+
+import (
+	"os"
+	"flag"
+	"time"
+	"bytes"
+	"errors"
+	"strings"
+	"reflect"
+	"os/exec"
+	"path/filepath"
+	"encoding/json"
+
+	"github.com/go-cmd/cmd"
+	"github.com/mitchellh/mapstructure"
+)
+
+/* server configuration - JSON file in config dir (or -config), command-line flags override file settings
+ * reload_config request/SIGHUP applies changes live where possible (see apply_config()) */
+
+const config_file = "fnordstream.json"      // in config dir (see config_path)
+
+/* external command (player etc.) - empty path: look up name in PATH
+ * env is added to the environment of fnordstream, dir is the working directory (default: ours)
+ * mpv started by streamlink and yt-dlp started by mpv inherit the environment of their parent */
+type CommandConfig struct {
+	Path                string              `json:"path,omitempty" mapstructure:"path"`
+	Env                 map[string]string   `json:"env,omitempty" mapstructure:"env"`
+	Dir                 string              `json:"dir,omitempty" mapstructure:"dir"`
+}
+
+/* configurable commands (players setting) */
+var config_commands = map[string]bool{ "mpv" : true, "streamlink" : true, "yt-dlp" : true, "xrandr" : true }
+
+type ServerConfig struct {
+	Listen_addr         string            `json:"listen_addr" mapstructure:"listen_addr"`
+	Allowed_ips         string            `json:"allowed_ips" mapstructure:"allowed_ips"`
+	Allowed_origins     string            `json:"allowed_origins" mapstructure:"allowed_origins"`
+	No_web              bool              `json:"no_web" mapstructure:"no_web"`
+	Web_dir             string            `json:"web_dir" mapstructure:"web_dir"`
+
+	Log_level           string            `json:"log_level" mapstructure:"log_level"`
+	Log_file            string            `json:"log_file" mapstructure:"log_file"`
+	Stream_debug        bool              `json:"stream_debug" mapstructure:"stream_debug"`
+
+	Pid_file            string            `json:"pid_file" mapstructure:"pid_file"`
+	Autostart           string            `json:"autostart" mapstructure:"autostart"`
+	Resume              bool              `json:"resume" mapstructure:"resume"`
+	Ipc_dir             string            `json:"ipc_dir" mapstructure:"ipc_dir"`     // base dir for IPC sockets (default: $XDG_RUNTIME_DIR or temp dir)
+
+	Record_dir          string            `json:"record_dir" mapstructure:"record_dir"`
+	Record_template     string            `json:"record_template" mapstructure:"record_template"`
+
+	/* playback defaults - start_streams requests can override options, watchdog & thumbnail_interval */
+	Restart_error_delay float64           `json:"restart_error_delay" mapstructure:"restart_error_delay"`  // seconds
+	Playback_options    map[string]bool   `json:"playback_options" mapstructure:"playback_options"`
+	Watchdog            WatchdogConfig    `json:"watchdog" mapstructure:"watchdog"`
+	Thumbnail_interval  float64           `json:"thumbnail_interval" mapstructure:"thumbnail_interval"`    // seconds (0: on request only)
+	Mpv_args          []string            `json:"mpv_args" mapstructure:"mpv_args"`                        // added to the default mpv args
+	Streamlink_args   []string            `json:"streamlink_args" mapstructure:"streamlink_args"`
+
+	Players             map[string]*CommandConfig  `json:"players" mapstructure:"players"`      // see config_commands
+}
+
+/* settings which need a restart - changes are reported by reload_config */
+var config_restart_keys = []string{"listen_addr", "no_web", "web_dir", "log_file", "pid_file", "autostart", "ipc_dir"}
+
+func default_config() *ServerConfig {
+	return &ServerConfig{
+		Listen_addr         : "localhost:8090",
+		Allowed_ips         : "<ANY>",
+		Web_dir             : "web",
+		Log_level           : "info",
+		Record_dir          : "recordings",
+		Record_template     : default_record_template,
+		Restart_error_delay : 1,
+		Playback_options    : map[string]bool{},
+		Watchdog            : WatchdogConfig{ Stall_timeout : 30, Cache_timeout : 60 },
+		Players             : map[string]*CommandConfig{},
+	}
+}
+
+/* settings for external command name (defaults if not configured) */
+func (cfg *ServerConfig) command(name string) *CommandConfig {
+	res := &CommandConfig{}
+	if c := cfg.Players[name]; c != nil { *res = *c }
+	if res.Path == "" { res.Path = name }
+	return res
+}
+
+/* configured path - absolute path of the binary that will be executed */
+func (c *CommandConfig) resolve() (string, error) {
+	path, err := exec.LookPath(c.Path)
+	if err != nil { return "", err }
+	return filepath.Abs(path)
+}
+
+/* path passed to other commands (mpv --script-opts, streamlink --player) - resolved here since
+ * they run in their own dir, unresolved if not found (start fails & reports it) */
+func (c *CommandConfig) arg_path() string {
+	if path, err := c.resolve(); err == nil { return path }
+	return c.Path
+}
+
+/* command w/ env & dir - relative paths are resolved in our working directory (not dir) */
+func (c *CommandConfig) new_cmd(options cmd.Options, args ...string) *cmd.Cmd {
+	path := c.Path
+	if resolved, err := c.resolve(); err == nil { path = resolved }    // error is reported by Start()
+	res  := cmd.NewCmdOptions(options, path, args...)
+	res.Dir = c.Dir
+	if len(c.Env) > 0 {
+		res.Env = os.Environ()
+		for k, v := range c.Env { res.Env = append(res.Env, k + "=" + v) }    // last one wins
+	}
+	return res
+}
+
+func (cfg *ServerConfig) validate() error {
+	if _, err := parse_log_level(cfg.Log_level); err != nil { return err }
+	if cfg.Restart_error_delay < 0 { return errors.New("restart_error_delay must not be negative") }
+	if cfg.Thumbnail_interval < 0 { return errors.New("thumbnail_interval must not be negative") }
+	for name, c := range cfg.Players {
+		if !config_commands[name] { return errors.New("unknown command " + name + " in players") }
+		if c == nil { return errors.New("players: " + name + ": settings missing") }
+		if (name == "yt-dlp") && strings.Contains(c.Path, ",") {      // separates mpv --script-opts entries
+			return errors.New("players: yt-dlp: path must not contain a comma")
+		}
+		for k := range c.Env {
+			if (k == "") || strings.ContainsAny(k, "=\x00") { return errors.New("players: " + name + ": invalid env name " + k) }
+		}
+	}
+	return nil
+}
+
+/* settings given on the command line (flag names w/ - replaced by _) */
+func config_flag_overrides() map[string]interface{} {
+	res := map[string]interface{}{}
+	flag.Visit(func(f *flag.Flag) {
+		if getter, ok := f.Value.(flag.Getter); ok {
+			res[strings.ReplaceAll(f.Name, "-", "_")] = getter.Get()
+		}
+	})
+	return res
+}
+
+/* defaults, then config file (optional unless required), then overrides (unknown keys are ignored) */
+func config_load(fname string, required bool, overrides map[string]interface{}) (*ServerConfig, error) {
+	cfg := default_config()
+	content, err := os.ReadFile(fname)
+	if (err != nil) && (required || !errors.Is(err, os.ErrNotExist)) { return nil, err }
+	if err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()                    // catch typos
+		if err := decoder.Decode(cfg); err != nil { return nil, errors.New(fname + ": " + err.Error()) }
+	}
+	if err := mapstructure.Decode(overrides, cfg); err != nil { return nil, err }
+	if err := cfg.validate(); err != nil { return nil, errors.New(fname + ": " + err.Error()) }
+	return cfg, nil
+}
+
+/* apply settings to hub - executed in StreamHub.Run() context (or before)
+ * playback settings take effect with the next start_streams */
+func (hub *StreamHub) apply_config(cfg *ServerConfig) {
+	level, _ := parse_log_level(cfg.Log_level)
+	log_level.Set(level)
+	stream_debug.Store(cfg.Stream_debug)
+
+	hub.restart_error_delay = time.Duration(cfg.Restart_error_delay * float64(time.Second))
+	hub.watchdog            = cfg.Watchdog
+	hub.thumbnail_interval  = time.Duration(cfg.Thumbnail_interval * float64(time.Second))
+	hub.record_dir          = cfg.Record_dir
+	hub.record_template     = cfg.Record_template
+	hub.resume_session      = cfg.Resume
+	hub.config              = cfg
+}
+
+/* settings changed since startup which need a restart */
+func config_restart_needed(old, cur *ServerConfig) []string {
+	res := []string{}
+	old_v, cur_v := reflect.ValueOf(old).Elem(), reflect.ValueOf(cur).Elem()
+	for idx := 0; idx < old_v.NumField(); idx++ {
+		key := old_v.Type().Field(idx).Tag.Get("mapstructure")
+		for _, restart_key := range config_restart_keys {
+			if (key == restart_key) && !reflect.DeepEqual(old_v.Field(idx).Interface(), cur_v.Field(idx).Interface()) {
+				res = append(res, key)
+			}
+		}
+	}
+	return res
+}
+
+func config_failed(hub *StreamHub, client *Client, err error) {
+	hub.log.Warn("config reload failed", "file", hub.config_file, "err", err)
+	res := map[string]interface{}{
+		"file"  : hub.config_file,
+		"error" : err.Error(),
+	}
+	hub.send_response(client, "config_failed", res)
+}
+
+/* reload config file & apply - also triggered by SIGHUP (client == nil) */
+func reload_config(hub *StreamHub, client *Client, request map[string]interface {}) {
+	cfg, err := config_load(hub.config_file, hub.config_required, hub.config_overrides)
+	if err == nil { err = webif_reconfigure(cfg) }
+	if err != nil {
+		config_failed(hub, client, err)
+		return
+	}
+	restart := config_restart_needed(hub.config_initial, cfg)
+	hub.apply_config(cfg)
+	hub.log.Info("config reloaded", "file", hub.config_file, "restart_needed", restart)
+	res := map[string]interface{}{
+		"file"           : hub.config_file,
+		"restart_needed" : restart,
+	}
+	hub.send_response(client, "reload_config", res)
+}
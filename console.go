@@ -1,100 +1,282 @@
 package main
 
 import (
+	"io"
 	"fmt"
 	"os"
-	"bufio"
+	"errors"
+	"strconv"
 	"strings"
-	"regexp"
+	"unicode"
 )
 
-func load_file(specname string, streams *[]interface{}, viewports *[]interface{}, options map[string]bool) {
-	fh := os.Stdin
-	if specname != "-" {
-		fh, _ = os.Open(specname)
-	}
-	reader    := bufio.NewReader(fh)
-	re := regexp.MustCompile(`(\S+)\s*=\s*(\S+)`)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
+/* console mode stream list format - one entry per line:
+ *
+ *   # comment                      (also at the end of a line)
+ *   option <name>=<value>          global option, e.g. option start_muted=no
+ *   <location> [W H X Y] [key=value ...]
+ *
+ * locations and values can be quoted ("..." or '...') to include spaces, '#' or '='
+ * spaces in URL locations are encoded (%20) - other locations must not contain spaces
+ * per-stream keys: title, user-agent, referrer, backup (may be repeated)
+ * legacy lines "<option>=<value>" are accepted for known options
+ * M3U/PLS playlists are detected and imported (see playlist_import.go) */
+
+type ConsoleSpec struct {
+	streams           []interface{}
+	viewports         []interface{}
+	options             map[string]bool
+	extra               map[string]interface{}    // additional start_streams parameters
+}
+
+/* split line into tokens - quotes group, '#' at token start begins a comment */
+func console_tokens(line string) ([]string, error) {
+	res     := []string{}
+	token   := strings.Builder{}
+	in_tok  := false
+	quote   := rune(0)
+	for _, r := range line {
+		switch {
+			case quote != 0:
+				if r == quote { quote = 0 } else { token.WriteRune(r) }
+			case (r == '"') || (r == '\''):
+				quote, in_tok = r, true
+			case unicode.IsSpace(r):
+				if in_tok { res = append(res, token.String()) }
+				token.Reset()
+				in_tok = false
+			case (r == '#') && !in_tok:
+				return res, nil
+			default:
+				token.WriteRune(r)
+				in_tok = true
 		}
-		var uri string
-		var geo Geometry
-		res, _ := fmt.Sscanf(line,"%s %d %d %d %d",&uri,&geo.W,&geo.H,&geo.X,&geo.Y)
-		if res >= 1 {
-			if strings.Contains(line,"=") && !strings.Contains(line,"http") { // ugly hack for now
-				res := re.FindStringSubmatch(line)
-				if len(res) < 3 { continue }
-				options[res[1]] = res[2] == "true"
+	}
+	if quote != 0 { return nil, errors.New("unterminated quote") }
+	if in_tok { res = append(res, token.String()) }
+	return res, nil
+}
+
+func parse_bool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+		case "yes", "true", "on", "1"  : return true, nil
+		case "no", "false", "off", "0" : return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", val)
+}
+
+/* global option: playback options (bool), thumbnail_interval, watchdog settings */
+func (spec *ConsoleSpec) option(name string, val string) error {
+	if profile_options[name] {
+		flag, err := parse_bool(val)
+		spec.options[name] = flag
+		return err
+	}
+	watchdog, _ := spec.extra["watchdog"].(map[string]interface{})
+	if watchdog == nil { watchdog = map[string]interface{}{} }
+	switch name {
+		case "thumbnail_interval", "stall_timeout", "cache_timeout":
+			num, err := strconv.ParseFloat(val, 64)
+			if (err != nil) || (num < 0) { return fmt.Errorf("invalid number %q", val) }
+			if name == "thumbnail_interval" {
+				spec.extra[name] = num
 			} else {
-				*streams = append(*streams, uri)
-				fmt.Print("added "+line)
-				if res == 5 {
-					*viewports = append(*viewports, geo)
-				}
+				watchdog[name] = num
+			}
+		case "watchdog_restart":
+			flag, err := parse_bool(val)
+			if err != nil { return err }
+			watchdog["restart"] = flag
+		default:
+			return fmt.Errorf("unknown option %q", name)
+	}
+	if len(watchdog) > 0 { spec.extra["watchdog"] = watchdog }
+	return nil
+}
+
+/* quoted location - the location sanitizer (location_re) strips spaces */
+func console_location(token string) (string, error) {
+	location := strings.TrimSpace(token)
+	if !strings.Contains(location, " ") { return location, nil }
+	if !strings.Contains(location, "://") { return "", fmt.Errorf("spaces not supported in location %q", location) }
+	return strings.ReplaceAll(location, " ", "%20"), nil
+}
+
+/* <location> [W H X Y] [key=value ...] */
+func (spec *ConsoleSpec) stream(tokens []string) error {
+	location, err := console_location(tokens[0])
+	if err != nil { return err }
+	entry   := map[string]interface{}{ "locations" : []interface{}{location} }
+	geo     := []int{}
+	options := map[string]interface{}{}
+	backups := []interface{}{}
+	for _, token := range tokens[1:] {
+		if num, err := strconv.Atoi(token); err == nil {
+			if (len(geo) >= 4) || (len(options) > 0) || (len(entry) > 1) {
+				return fmt.Errorf("unexpected number %q", token)
 			}
+			geo = append(geo, num)
+			continue
+		}
+		kv := strings.SplitN(token, "=", 2)
+		if len(kv) < 2 { return fmt.Errorf("unexpected %q (key=value expected)", token) }
+		switch key, val := kv[0], kv[1]; key {
+			case "title"                 : entry["title"] = val
+			case "user-agent", "referrer": options[key] = val
+			case "backup"                :
+				backup, err := console_location(val)
+				if err != nil { return err }
+				backups = append(backups, backup)
+			default                      : return fmt.Errorf("unknown stream option %q", key)
 		}
 	}
-	fh.Close()
+	if (len(geo) != 0) && (len(geo) != 4) { return errors.New("geometry needs W H X Y") }
+	if len(options) > 0 { entry["options"] = options }
+	if len(backups) > 0 { entry["backups"] = backups }
+	if _, ok := parse_stream_entry(entry, location_re); !ok { return errors.New("invalid stream entry") }
+
+	if len(entry) == 1 {
+		spec.streams = append(spec.streams, location)
+	} else {
+		spec.streams = append(spec.streams, entry)
+	}
+	if len(geo) == 4 {
+		spec.viewports = append(spec.viewports, Geometry{ W : geo[0], H : geo[1], X : geo[2], Y : geo[3] })
+	}
+	return nil
 }
 
-func console_client(shub *StreamHub, specname string, wait bool) {
-	fmt.Println("adding streams via console client")
+func (spec *ConsoleSpec) parse_line(line string) error {
+	tokens, err := console_tokens(line)
+	if (err != nil) || (len(tokens) < 1) { return err }
 
-	profiles := map[string]interface{}{};
-	load_json("stream_profiles.json", &profiles);
+	/* option name=value, legacy name=value / name = value */
+	if (tokens[0] == "option") && (len(tokens) == 2) {
+		kv := strings.SplitN(tokens[1], "=", 2)
+		if len(kv) < 2 { return errors.New("option needs name=value") }
+		return spec.option(kv[0], kv[1])
+	}
+	if (len(tokens) == 3) && (tokens[1] == "=") && profile_options[tokens[0]] {
+		return spec.option(tokens[0], tokens[2])
+	}
+	if kv := strings.SplitN(tokens[0], "=", 2); (len(tokens) == 1) && (len(kv) == 2) && profile_options[kv[0]] {
+		return spec.option(kv[0], kv[1])
+	}
+	if tokens[0] == "option" { return errors.New("option needs name=value") }
+	return spec.stream(tokens)
+}
 
-	streams   := []interface{}{};
-	viewports := []interface{}{};
-	options   := map[string]bool{  // add some sane? defaults
-		"start_muted"   : true,
-		"restart_error" : true,
-	};
+/* load stream list from file or stdin (-) - errors are reported w/ line numbers */
+func load_file(specname string, spec *ConsoleSpec) error {
+	fh := os.Stdin
+	if specname != "-" {
+		var err error
+		if fh, err = os.Open(specname); err != nil { return err }
+	}
+	content, err := io.ReadAll(fh)
+	fh.Close()
+	if err != nil { return err }
 
-	/* try loading spec from JSON profiles first */
-	profile, ok := profiles[specname].(map[string]interface{})
-	if ok {
-		streams, _   = profile["stream_locations"].([]interface{})
-		if profile["viewports"] != nil {
-			viewports, _ = profile["viewports"].([]interface{})
+	/* M3U/PLS playlist? */
+	if playlist_format(string(content)) != "" {
+		entries, err := import_playlist_text(string(content))
+		for _, entry := range entries {
+			spec.streams = append(spec.streams, entry)
+			logger("console").Info("added stream", "entry", entry)
 		}
-		if profile["options"] != nil {
-			options, _   = profile["options"].(map[string]bool)
+		return err
+	}
+
+	errs := []error{}
+	for idx, line := range strings.Split(string(content), "\n") {
+		n_streams := len(spec.streams)
+		if err := spec.parse_line(line); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %w", specname, idx+1, err))
+		} else if len(spec.streams) > n_streams {
+			logger("console").Info("added stream", "line", idx+1, "entry", spec.streams[n_streams])
 		}
 	}
+	if (len(spec.viewports) > 0) && (len(spec.viewports) < len(spec.streams)) {
+		errs = append(errs, fmt.Errorf("%s: geometry given for %d of %d streams", specname, len(spec.viewports), len(spec.streams)))
+	}
+	return errors.Join(errs...)
+}
 
-	if len(streams)<1 {
-		load_file(specname, &streams, &viewports, options)
+/* start streams from profile or list file
+ * wait: keep running (no web UI), interactive: run REPL on stdin (see console_repl.go) */
+func console_client(shub *StreamHub, specname string, wait bool, interactive bool) {
+	log := logger("console")
+	log.Info("adding streams via console client")
+
+	profiles := profiles_load()
+
+	spec := &ConsoleSpec{
+		options : map[string]bool{  // add some sane? defaults
+			"start_muted"   : true,
+			"restart_error" : true,
+		},
+		extra   : map[string]interface{}{},
 	}
 
-	if len(streams) < 1 {
-		fmt.Println("no streams given - nothing to do")
+	/* try loading spec from JSON profiles first */
+	if profile, ok := profiles[specname]; ok {
+		spec.streams = profile.Stream_locations
+		for _, vp := range profile.Viewports {
+			spec.viewports = append(spec.viewports, vp)
+		}
+		for k, v := range profile.Options {
+			spec.options[k] = v
+		}
+	} else if err := load_file(specname, spec); err != nil {
+		log.Error("cannot load stream list\n" + err.Error())
+		return
+	}
+
+	if len(spec.streams) < 1 {
+		log.Warn("no streams given - nothing to do")
 		return
 	}
 
 	client := &Client{
 		client_request : make(chan map[string]interface{}),
+		remote         : "console",
 	}
-	if wait {
-		client.client_notify = make(chan []byte)
+	if wait || interactive {
+		client.client_notify = make(chan []byte, 256)
 	}
 	shub.Register <- client
 
 	msg := map[string]interface{}{
 		"request"   : "start_streams",
-		"streams"   : streams,
-		"viewports" : viewports,
-		"options"   : options,
+		"streams"   : spec.streams,
+		"viewports" : spec.viewports,
+		"options"   : spec.options,
 	}
+	for k, v := range spec.extra { msg[k] = v }
 
 	client.client_request <- msg
 
-	if wait {
-		for {
-			<- client.client_notify
+	if interactive {
+		repl := func() {
+			console_repl(client)
+			shub.Unregister <- client
+			close(client.client_request)
+		}
+		if !wait {         // web UI keeps running - quit terminates fnordstream
+			go func() {
+				repl()
+				shub.Shutdown(shutdown_timeout)
+				os.Exit(0)
+			}()
+			return
 		}
+		repl()
+		return
+	}
+
+	if wait {
+		for range client.client_notify {}     // closed if disconnected by the hub (too slow) - shut down
+		log.Warn("disconnected from hub - shutting down")
 	}
 
 	shub.Unregister <- client
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"io"
+	"time"
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+	"encoding/json"
+)
+
+/* interactive console: commands are sent as requests through the console Client,
+ * notifications (player status etc.) are printed as they arrive */
+
+type ReplCommand struct {
+	usage               string
+	run                 func(repl *ConsoleRepl, args []string) error
+}
+
+type ConsoleRepl struct {
+	client             *Client
+	out                 io.Writer
+	playing             bool
+	quit                bool
+	prompt_shown        bool      // "> " printed, nothing typed/printed since
+}
+
+/* notification as sent to clients */
+type ClientNote struct {
+	Notification        string            `json:"notification"`
+	Stream_id          *int               `json:"stream_id"`
+	Payload             json.RawMessage   `json:"payload"`
+}
+
+/* stdin is a terminal? */
+func console_interactive() bool {
+	fi, err := os.Stdin.Stat()
+	return (err == nil) && ((fi.Mode() & os.ModeCharDevice) != 0)
+}
+
+/* stream selector: index, * or all */
+func repl_stream_id(arg string) (interface{}, error) {
+	if (arg == "*") || (arg == "all") { return "*", nil }
+	id, err := strconv.Atoi(arg)
+	if (err != nil) || (id < 0) { return nil, fmt.Errorf("invalid stream %q", arg) }
+	return float64(id), nil
+}
+
+func (repl *ConsoleRepl) request(request map[string]interface{}) {
+	repl.client.client_request <- request
+}
+
+/* stream_ctl w/ stream selector from args[0] */
+func repl_ctl(ctl string, value interface{}) func(repl *ConsoleRepl, args []string) error {
+	return func(repl *ConsoleRepl, args []string) error {
+		if len(args) < 1 { return errors.New("stream missing") }
+		stream_id, err := repl_stream_id(args[0])
+		if err != nil { return err }
+		val := value
+		if val == nil {                          // value from args
+			if len(args) < 2 { return errors.New("value missing") }
+			val = args[1]
+		}
+		if _, err := stream_ctls[ctl](val, nil); err != nil { return err }
+		repl.request(map[string]interface{}{
+			"request"   : "stream_ctl",
+			"stream_id" : stream_id,
+			"ctl"       : ctl,
+			"value"     : val,
+		})
+		return nil
+	}
+}
+
+var repl_commands map[string]*ReplCommand
+
+func init() {    // init() avoids initialization cycle (help uses repl_commands)
+	repl_commands = map[string]*ReplCommand{
+		"help"    : { "help",                  repl_help },
+		"status"  : { "status",                repl_status },
+		"start"   : { "start <N|all>",         repl_ctl("play", "yes") },
+		"stop"    : { "stop [N|all]",          repl_stop },
+		"restart" : { "restart <N|all>",       repl_ctl("play", "restart") },
+		"mute"    : { "mute <N|all>",          repl_ctl("mute", "yes") },
+		"unmute"  : { "unmute <N|all>",        repl_ctl("mute", "no") },
+		"volume"  : { "volume <N|all> <0-130>", repl_ctl("volume", nil) },
+		"load"    : { "load <profile>",        repl_load },
+		"quit"    : { "quit",                  repl_quit },
+	}
+}
+
+func repl_help(repl *ConsoleRepl, args []string) error {
+	for _, name := range []string{"status", "start", "stop", "restart", "mute", "unmute", "volume", "load", "quit"} {
+		fmt.Fprintln(repl.out, "  " + repl_commands[name].usage)
+	}
+	return nil
+}
+
+func repl_status(repl *ConsoleRepl, args []string) error {
+	repl.request(map[string]interface{}{ "request" : "global_status" })
+	return nil
+}
+
+/* stop w/o stream: stop all streams */
+func repl_stop(repl *ConsoleRepl, args []string) error {
+	if len(args) > 0 { return repl_ctl("play", "no")(repl, args) }
+	repl.request(map[string]interface{}{ "request" : "stop_streams" })
+	return nil
+}
+
+func repl_load(repl *ConsoleRepl, args []string) error {
+	if len(args) < 1 { return errors.New("profile name missing") }
+	repl.request(map[string]interface{}{
+		"request"      : "profile_start",
+		"profile_name" : strings.Join(args, " "),
+	})
+	return nil
+}
+
+func repl_quit(repl *ConsoleRepl, args []string) error {
+	repl.quit = true
+	return nil
+}
+
+func (repl *ConsoleRepl) exec(line string) {
+	args, err := console_tokens(line)
+	if (err == nil) && (len(args) < 1) { return }
+	if err == nil {
+		cmd, ok := repl_commands[args[0]]
+		if !ok {
+			err = fmt.Errorf("unknown command %q - try help", args[0])
+		} else {
+			err = cmd.run(repl, args[1:])
+		}
+	}
+	if err != nil { fmt.Fprintln(repl.out, "error:", err) }
+}
+
+func (repl *ConsoleRepl) print_status(payload json.RawMessage) {
+	status := struct {
+		Playing         bool              `json:"playing"`
+		Streams       []*StreamStatus     `json:"streams"`
+	}{}
+	if json.Unmarshal(payload, &status) != nil { return }
+	repl.playing = status.Playing
+	if !status.Playing {
+		fmt.Fprintln(repl.out, "no streams playing")
+		return
+	}
+	fmt.Fprintf(repl.out, "%-4s %-10s %-7s %-5s %s\n", "id", "status", "volume", "mute", "title/location")
+	for idx, stream := range status.Streams {
+		if stream == nil { continue }
+		volume, mute := "-", "-"
+		if v, ok := stream.Properties["volume"].(float64); ok { volume = strconv.Itoa(int(v)) }
+		if m, ok := stream.Properties["mute"].(bool); ok { mute = strconv.FormatBool(m) }
+		title := stream.Title
+		if t, ok := stream.Properties["media-title"].(string); ok && (title == "") { title = t }
+		if title == "" { title = stream.Location }
+		fmt.Fprintf(repl.out, "%-4d %-10s %-7s %-5s %s\n", idx, stream.Player_status, volume, mute, title)
+	}
+}
+
+/* print notifications of interest - returns true if something was printed */
+func (repl *ConsoleRepl) notification(msg []byte) bool {
+	note := &ClientNote{}
+	if json.Unmarshal(msg, note) != nil { return false }
+	switch note.Notification {
+		case "global_status":
+			repl.clear_prompt()
+			repl.print_status(note.Payload)
+		case "player_status":
+			status := &PlayerStatus{}
+			if (note.Stream_id == nil) || (json.Unmarshal(note.Payload, status) != nil) { return false }
+			line := fmt.Sprintf("[%d] %s", *note.Stream_id, status.Status)
+			if status.Exit_code != nil { line += fmt.Sprintf(" (exit code %d)", *status.Exit_code) }
+			if status.Last_error != "" { line += ": " + status.Last_error }
+			repl.clear_prompt()
+			fmt.Fprintln(repl.out, line)
+		case "profile_failed", "schedule_failed", "import_failed", "config_failed", "log_ctl_failed":
+			res := struct { Error string `json:"error"` }{}
+			json.Unmarshal(note.Payload, &res)
+			repl.clear_prompt()
+			fmt.Fprintf(repl.out, "%s: %s\n", note.Notification, res.Error)
+		case "schedule_fired":
+			repl.clear_prompt()
+			fmt.Fprintln(repl.out, "scheduled action fired")
+		default:
+			return false
+	}
+	return true
+}
+
+/* prompt is redrawn once notifications stopped arriving */
+const repl_prompt_delay = 200 * time.Millisecond
+
+/* notification output overwrites a pending prompt */
+func (repl *ConsoleRepl) clear_prompt() {
+	if !repl.prompt_shown { return }
+	fmt.Fprint(repl.out, "\r")
+	repl.prompt_shown = false
+}
+
+/* run REPL on stdin until quit/EOF - stops all streams on exit */
+func console_repl(client *Client) {
+	repl  := &ConsoleRepl{ client : client, out : os.Stdout }
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() { lines <- scanner.Text() }
+		close(lines)
+	}()
+
+	fmt.Fprintln(repl.out, "interactive console - type help for a list of commands")
+	repl.request(map[string]interface{}{ "request" : "global_status" })
+	redraw := time.NewTimer(repl_prompt_delay)     // initial prompt after the status reply
+	defer redraw.Stop()
+	for !repl.quit {
+		select {
+			case line, ok := <-lines:
+				if !ok { repl.quit = true; break }
+				repl.prompt_shown = false            // line ended w/ newline
+				repl.exec(line)
+				redraw.Reset(repl_prompt_delay)      // replies of the command first
+			case msg, ok := <-client.client_notify:
+				if !ok { return }
+				if repl.notification(msg) { redraw.Reset(repl_prompt_delay) }
+			case <-redraw.C:
+				if !repl.prompt_shown { fmt.Fprint(repl.out, "> ") }
+				repl.prompt_shown = true
+		}
+	}
+	fmt.Fprintln(repl.out)
+
+	/* stop streams & wait for the hub to confirm */
+	if !repl.playing { return }
+	repl.request(map[string]interface{}{ "request" : "stop_streams" })
+	timeout := time.After(5 * time.Second)
+	for repl.playing {
+		select {
+			case msg, ok := <-client.client_notify:
+				if !ok { return }
+				repl.notification(msg)
+			case <-timeout:
+				return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConsoleTokens(t *testing.T) {
+	tests := []struct {
+		line        string
+		tokens    []string
+		err         bool
+	}{
+		{ "",                                       []string{},                                         false },
+		{ "   ",                                    []string{},                                         false },
+		{ "# comment",                              []string{},                                         false },
+		{ "https://a/b",                            []string{"https://a/b"},                            false },
+		{ "  https://a/b  \t 640 360 0 0 ",         []string{"https://a/b", "640", "360", "0", "0"},    false },
+		{ "https://a/b # comment",                  []string{"https://a/b"},                            false },
+		{ "https://a/b#frag",                       []string{"https://a/b#frag"},                       false },
+		{ `"my file.mp4" title="Cam 1"`,            []string{"my file.mp4", "title=Cam 1"},             false },
+		{ `'single "quoted"'`,                      []string{`single "quoted"`},                        false },
+		{ `"# not a comment"`,                      []string{"# not a comment"},                        false },
+		{ `title="a=b"`,                            []string{"title=a=b"},                              false },
+		{ `""`,                                     []string{""},                                       false },
+		{ `a"b c"d`,                                []string{"ab cd"},                                  false },
+		{ `"unterminated`,                          nil,                                                true },
+		{ `title='open`,                            nil,                                                true },
+	}
+	for _, test := range tests {
+		tokens, err := console_tokens(test.line)
+		if (err != nil) != test.err {
+			t.Errorf("console_tokens(%q): err = %v, want err %v", test.line, err, test.err)
+			continue
+		}
+		if !test.err && !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("console_tokens(%q) = %q, want %q", test.line, tokens, test.tokens)
+		}
+	}
+}
+
+func TestConsoleLocation(t *testing.T) {
+	tests := []struct {
+		token       string
+		location    string
+		err         bool
+	}{
+		{ "https://a/b",                  "https://a/b",                false },
+		{ " https://a/b \t",              "https://a/b",                false },
+		{ "https://a/my stream.m3u8",     "https://a/my%20stream.m3u8", false },
+		{ "video.mp4",                    "video.mp4",                  false },
+		{ "my file.mp4",                  "",                           true },
+	}
+	for _, test := range tests {
+		location, err := console_location(test.token)
+		if (err != nil) != test.err {
+			t.Errorf("console_location(%q): err = %v, want err %v", test.token, err, test.err)
+			continue
+		}
+		if location != test.location {
+			t.Errorf("console_location(%q) = %q, want %q", test.token, location, test.location)
+		}
+	}
+}
+
+func TestConsoleSpecLines(t *testing.T) {
+	tests := []struct {
+		line        string
+		streams   []interface{}
+		err         bool
+	}{
+		{ "https://a/b",                          []interface{}{"https://a/b"}, false },
+		{ "https://a/b 1 2 3",                    nil,                          true },
+		{ "https://a/b bogus=1",                  nil,                          true },
+		{ "https://a/b title=Cam",                []interface{}{map[string]interface{}{
+			"locations" : []interface{}{"https://a/b"},
+			"title"     : "Cam",
+		}}, false },
+		{ "https://a/b backup=https://c/d backup=https://e/f", []interface{}{map[string]interface{}{
+			"locations" : []interface{}{"https://a/b"},
+			"backups"   : []interface{}{"https://c/d", "https://e/f"},
+		}}, false },
+		{ "https://a/b title=Cam 1 2 3 4",        nil,                          true },    // geometry after options
+	}
+	for _, test := range tests {
+		spec   := &ConsoleSpec{ options : map[string]bool{}, extra : map[string]interface{}{} }
+		tokens, _ := console_tokens(test.line)
+		err    := spec.stream(tokens)
+		if (err != nil) != test.err {
+			t.Errorf("stream(%q): err = %v, want err %v", test.line, err, test.err)
+			continue
+		}
+		if !test.err && !reflect.DeepEqual(spec.streams, test.streams) {
+			t.Errorf("stream(%q) = %#v, want %#v", test.line, spec.streams, test.streams)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"net"
+	"flag"
+	"sort"
+	"time"
+	"errors"
+	"strings"
+	"net/url"
+	"encoding/json"
+	"text/tabwriter"
+
+	"github.com/gorilla/websocket"
+)
+
+/* fnordstream ctl - command-line client for a running instance (websocket /ws) */
+
+/* failure notifications - await() returns them as error */
+var ctl_failures = map[string]bool{
+	"profile_failed"  : true,
+	"schedule_failed" : true,
+	"import_failed"   : true,
+	"config_failed"   : true,
+	"log_ctl_failed"  : true,
+}
+
+/* reply notification of requests (request command)
+ * other requests have no reply - their completion is confirmed by a global_status round trip */
+var ctl_replies = map[string]string{
+	"global_status"          : "global_status",
+	"probe_commands"         : "probe_commands",
+	"log_ctl"                : "log_ctl",
+	"reload_config"          : "reload_config",
+	"get_profiles"           : "profiles",
+	"profile_save"           : "profiles",
+	"profile_import"         : "profiles",
+	"profile_export"         : "profile_export",
+	"profile_history"        : "profile_history",
+	"profile_restore"        : "profile_history",
+	"import_playlist"        : "imported_playlist",
+	"schedule_add"           : "schedule",
+	"schedule_list"          : "schedule",
+	"detect_displays"        : "displays",
+	"get_displays"           : "displays",
+	"set_displays"           : "displays",
+	"suggest_viewports"      : "viewports",
+	"get_stream_log"         : "stream_log",
+	"subscribe_properties"   : "property_subscriptions",
+	"unsubscribe_properties" : "property_subscriptions",
+}
+
+type CtlConn struct {
+	conn               *websocket.Conn
+	notes               chan *ClientNote
+	timeout             time.Duration
+}
+
+func ctl_dial(listen_addr string, timeout time.Duration) (*CtlConn, error) {
+	host, port, err := net.SplitHostPort(listen_addr)
+	if err != nil { return nil, err }
+	if (host == "") || (host == "0.0.0.0") || (host == "::") { host = "localhost" }
+	u := url.URL{ Scheme : "ws", Host : net.JoinHostPort(host, port), Path : "/ws" }
+
+	dialer := websocket.Dialer{ HandshakeTimeout : timeout }
+	conn, _, err := dialer.Dial(u.String(), nil)
+	if err != nil { return nil, err }
+
+	ctl := &CtlConn{ conn : conn, notes : make(chan *ClientNote, 256), timeout : timeout }
+	go func() {                      // notifications may be batched (one JSON object per line)
+		defer close(ctl.notes)
+		for {
+			_, rd, err := conn.NextReader()
+			if err != nil { return }
+			decoder := json.NewDecoder(rd)
+			for decoder.More() {
+				note := &ClientNote{}
+				if decoder.Decode(note) != nil { break }
+				ctl.notes <- note
+			}
+		}
+	}()
+	return ctl, nil
+}
+
+func (ctl *CtlConn) send(request map[string]interface{}) error {
+	return ctl.conn.WriteJSON(request)
+}
+
+/* wait for notification accepted by match - failure notifications end the wait w/ an error */
+func (ctl *CtlConn) await(match func(note *ClientNote) bool) (*ClientNote, error) {
+	timeout := time.After(ctl.timeout)
+	for {
+		select {
+			case note, ok := <-ctl.notes:
+				if !ok { return nil, errors.New("connection closed") }
+				if ctl_failures[note.Notification] {
+					res := struct { Error string `json:"error"` }{}
+					json.Unmarshal(note.Payload, &res)
+					return note, fmt.Errorf("%s: %s", note.Notification, res.Error)
+				}
+				if match(note) { return note, nil }
+			case <-timeout:
+				return nil, errors.New("timeout waiting for reply")
+		}
+	}
+}
+
+func ctl_named(name string) func(note *ClientNote) bool {
+	return func(note *ClientNote) bool { return note.Notification == name }
+}
+
+/* check stream selector against the running streams (stream_ctl requests are ignored otherwise) */
+func (ctl *CtlConn) stream_check(stream_id interface{}) error {
+	if err := ctl.send(map[string]interface{}{ "request" : "global_status" }); err != nil { return err }
+	note, err := ctl.await(ctl_named("global_status"))
+	if err != nil { return err }
+	status := struct {
+		Playing         bool              `json:"playing"`
+		Streams       []*StreamStatus     `json:"streams"`
+	}{}
+	json.Unmarshal(note.Payload, &status)
+	if !status.Playing { return errors.New("no streams playing") }
+	if id_f, ok := stream_id.(float64); ok {           // see repl_stream_id()
+		if id := int(id_f); (id >= len(status.Streams)) || (status.Streams[id] == nil) {
+			return fmt.Errorf("no stream %d (%d streams playing)", id, len(status.Streams))
+		}
+	}
+	return nil
+}
+
+/* global_status w/ given playing state */
+func ctl_playing(playing bool) func(note *ClientNote) bool {
+	return func(note *ClientNote) bool {
+		if note.Notification != "global_status" { return false }
+		status := struct { Playing bool `json:"playing"` }{}
+		json.Unmarshal(note.Payload, &status)
+		return status.Playing == playing
+	}
+}
+
+func ctl_print_json(note *ClientNote) {
+	var val interface{}
+	json.Unmarshal(note.Payload, &val)
+	out, _ := json.MarshalIndent(val, "", "  ")
+	fmt.Println(string(out))
+}
+
+func ctl_print_status(note *ClientNote) {
+	status := struct {
+		Version         string            `json:"version"`
+		Playing         bool              `json:"playing"`
+		Streams       []*StreamStatus     `json:"streams"`
+	}{}
+	json.Unmarshal(note.Payload, &status)
+	if !status.Playing {
+		fmt.Println("no streams playing")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tMUTE\tVOLUME\tTITLE/LOCATION")
+	for idx, stream := range status.Streams {
+		if stream == nil { continue }
+		mute, volume := "-", "-"
+		if v, ok := stream.Properties["mute"].(bool); ok { mute = fmt.Sprint(v) }
+		if v, ok := stream.Properties["volume"].(float64); ok { volume = fmt.Sprintf("%.0f", v) }
+		title := stream.Title
+		if t, ok := stream.Properties["media-title"].(string); ok && (title == "") { title = t }
+		if title == "" { title = stream.Location }
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", idx, stream.Player_status, mute, volume, title)
+	}
+	tw.Flush()
+}
+
+func ctl_print_profiles(note *ClientNote) {
+	profiles := map[string]*Profile{}
+	json.Unmarshal(note.Payload, &profiles)
+	names := make([]string, 0, len(profiles))
+	for name := range profiles { names = append(names, name) }
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTREAMS\tVIEWPORTS")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", name, len(profiles[name].Stream_locations), len(profiles[name].Viewports))
+	}
+	tw.Flush()
+}
+
+/* key=value args of the generic request command - values are JSON or plain strings */
+func ctl_request_args(request map[string]interface{}, args []string) error {
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) < 2 { return fmt.Errorf("invalid argument %q (key=value expected)", arg) }
+		var val interface{}
+		if json.Unmarshal([]byte(kv[1]), &val) != nil { val = kv[1] }
+		request[kv[0]] = val
+	}
+	return nil
+}
+
+const ctl_usage = `usage: fnordstream [-listen-addr host:port] ctl [-json] [-timeout 5s] <command>
+
+commands:
+  status                              show streams
+  start <profile>                     start streams of a stored profile
+  stop                                stop all streams
+  stream <N|all> <ctl> [value]        stream control (e.g. stream 2 mute yes)
+  profiles                            list stored profiles
+  request <name> [key=value ...]      send any request, print reply if it has one (-wait <notification>)
+`
+
+/* run ctl subcommand - returns exit code */
+func ctl_main(listen_addr string, args []string) int {
+	flags    := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	as_json  := flags.Bool("json", false, "print replies as JSON")
+	timeout  := flags.Duration("timeout", 5*time.Second, "reply timeout")
+	wait_for := flags.String("wait", "", "notification to wait for (request command - default: request name)")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, ctl_usage) }
+	if flags.Parse(args) != nil { return 2 }
+	args = flags.Args()
+	if len(args) < 1 {
+		flags.Usage()
+		return 2
+	}
+
+	/* build request, reply matcher & printer for the command */
+	var request map[string]interface{}
+	var match func(note *ClientNote) bool
+	print := ctl_print_json
+	switch cmd := args[0]; {
+		case (cmd == "status") && (len(args) == 1):
+			request = map[string]interface{}{ "request" : "global_status" }
+			match   = ctl_named("global_status")
+			print   = ctl_print_status
+		case (cmd == "start") && (len(args) == 2):
+			request = map[string]interface{}{ "request" : "profile_start", "profile_name" : args[1] }
+			match   = ctl_playing(true)
+			print   = ctl_print_status
+		case (cmd == "stop") && (len(args) == 1):
+			request = map[string]interface{}{ "request" : "stop_streams" }
+			match   = ctl_playing(false)
+			print   = ctl_print_status
+		case (cmd == "stream") && ((len(args) == 3) || (len(args) == 4)):
+			stream_id, err := repl_stream_id(args[1])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR:", err)
+				return 2
+			}
+			handler, ok := stream_ctls[args[2]]
+			if !ok {
+				fmt.Fprintln(os.Stderr, "ERROR: unknown stream control", args[2])
+				return 2
+			}
+			var value interface{}
+			if len(args) == 4 { value = args[3] }
+			if _, err = handler(value, nil); err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR:", args[2] + ":", err)
+				return 2
+			}
+			request = map[string]interface{}{ "request" : "stream_ctl", "stream_id" : stream_id, "ctl" : args[2], "value" : value }
+		case (cmd == "profiles") && (len(args) == 1):
+			request = map[string]interface{}{ "request" : "get_profiles" }
+			match   = ctl_named("profiles")
+			print   = ctl_print_profiles
+		case (cmd == "request") && (len(args) >= 2):
+			if _, ok := req_handlers[args[1]]; !ok {
+				fmt.Fprintln(os.Stderr, "ERROR: unknown request", args[1])
+				return 2
+			}
+			request = map[string]interface{}{ "request" : args[1] }
+			if err := ctl_request_args(request, args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "ERROR:", err)
+				return 2
+			}
+			if name, ok := ctl_replies[args[1]]; ok { match = ctl_named(name) }
+			if *wait_for != "" { match = ctl_named(*wait_for) }
+		default:
+			flags.Usage()
+			return 2
+	}
+	if *as_json { print = ctl_print_json }
+
+	ctl, err := ctl_dial(listen_addr, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR: cannot connect to fnordstream:", err)
+		return 1
+	}
+	defer ctl.conn.Close()
+
+	if request["request"] == "stream_ctl" {
+		if err = ctl.stream_check(request["stream_id"]); err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR:", err)
+			return 1
+		}
+	}
+	if err = ctl.send(request); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		return 1
+	}
+	if request["request"] == "stop_streams" {      // no broadcast if nothing is playing
+		ctl.send(map[string]interface{}{ "request" : "global_status" })
+	}
+	if match == nil {                 // no reply - round trip to make sure the request was processed
+		ctl.send(map[string]interface{}{ "request" : "global_status" })
+		match = ctl_named("global_status")
+		print = func(note *ClientNote) {}
+	}
+	note, err := ctl.await(match)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		return 1
+	}
+	print(note)
+	return 0
+}
@@ -46,8 +46,8 @@ func pshell_read() []Display {
     return displays
 }
 
-func xrandr_read() []Display {
-	ctx    := cmd.NewCmd("xrandr", "--listactivemonitors")
+func xrandr_read(xrandr *CommandConfig) []Display {
+	ctx    := xrandr.new_cmd(cmd.Options{ Buffered : true }, "--listactivemonitors")
 	status := <-ctx.Start()
 
 	var displays  []Display
@@ -67,16 +67,16 @@ func xrandr_read() []Display {
     return displays
 }
 
-func displays_detect() []Display {
+func displays_detect(xrandr *CommandConfig) []Display {
 	res := []Display{}
 	switch runtime.GOOS {
 	case "windows":
 		res = pshell_read()
 	//case "darwin":
 	case "linux":
-		res = xrandr_read()
+		res = xrandr_read(xrandr)
 	default:
-		fmt.Println("no display detection for OS:",runtime.GOOS,"!")
+		logger("displays").Warn("no display detection for OS", "os", runtime.GOOS)
 	}
 	return res
 }
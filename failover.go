@@ -0,0 +1,128 @@
+package main
+
+import (
+	"time"
+	"encoding/json"
+	"github.com/go-cmd/cmd"
+)
+
+const (
+	default_failover_errors    = 3                 // consecutive player errors before switching source
+	default_failback_interval  = 60 * time.Second  // primary health check interval while on a backup
+)
+
+type SourceStatus struct {
+	Source              int         `json:"source"`      // 0: primary, n: backup n
+	Location            string      `json:"location"`
+}
+
+func (stream *Stream) n_sources() int {
+	return 1 + len(stream.player_cfg.playlist.Backups)
+}
+
+/* switch to primary (0) or backup (>0) location - takes effect on next player start */
+func (stream *Stream) source_select(idx int) {
+	config := stream.player_cfg
+	stream.source_idx  = idx
+	stream.error_count = 0
+	if idx == 0 {
+		config.location = config.playlist.Locations[stream.playlist_pos]
+		stream.failback_stop()
+	} else {
+		config.location = config.playlist.Backups[idx-1]
+		stream.failback_start()
+	}
+	stream.log.Info("switching source", "source", idx, "location", config.location)
+
+	status      := &SourceStatus{ Source : idx, Location : config.location }
+	json_msg, _ := json.Marshal(status)
+	stream.notifications <- &Notification{
+		stream_id    : stream.stream_id,
+		notification : "source_status",
+		payload      : status,
+		json_message : json_msg,
+	}
+}
+
+func (stream *Stream) failover_errors() int {
+	if max_errors := stream.player_cfg.playlist.Failover_errors; max_errors > 0 { return max_errors }
+	return default_failover_errors
+}
+
+/* all sources of the current playlist item failed (or there are no backups)
+ * playlist_advance() moves on to the next item on errors only then */
+func (stream *Stream) failover_exhausted() bool {
+	if stream.n_sources() < 2 { return true }
+	return (stream.source_idx == stream.n_sources()-1) && (stream.error_count >= stream.failover_errors())
+}
+
+/* called from schedule_restart() when a playing player exited
+ * returns restart delay if the source was switched, -1 otherwise */
+func (stream *Stream) failover(cmd_status *cmd.Status) time.Duration {
+	if stream.n_sources() < 2 { return -1 }
+	if (cmd_status.Exit < 1) || (cmd_status.Exit >= 127) { return -1 }
+
+	stream.error_count++
+	if stream.error_count < stream.failover_errors() { return -1 }
+
+	/* last backup failed - next playlist item instead of starting over w/ the primary */
+	if stream.failover_exhausted() && stream.player_cfg.playlist.Advance_on_error && (stream.playlist_len() > 1) {
+		return -1
+	}
+	stream.source_select((stream.source_idx + 1) % stream.n_sources())
+	return playlist_error_delay
+}
+
+/* playback started - source is healthy */
+func (stream *Stream) failover_ok() {
+	stream.error_count = 0
+}
+
+/* periodic health check of the primary location while playing a backup */
+func (stream *Stream) failback_start() {
+	if stream.failback_ticker_ch != nil { return }
+	interval := default_failback_interval
+	if secs := stream.player_cfg.playlist.Failback_interval; secs > 0 {
+		interval = time.Duration(secs * float64(time.Second))
+	}
+	stream.failback_ticker    = time.NewTicker(interval)
+	stream.failback_ticker_ch = stream.failback_ticker.C
+}
+
+/* stop health checks incl. a running probe */
+func (stream *Stream) failback_stop() {
+	if stream.probe_cmd != nil {
+		stream.probe_cmd.Stop()
+		stream.probe_cmd    = nil
+		stream.probe_status = nil
+	}
+	if stream.failback_ticker_ch == nil { return }
+	stream.failback_ticker.Stop()
+	stream.failback_ticker    = nil
+	stream.failback_ticker_ch = nil
+}
+
+/* probe primary location w/o playing it (yt-dlp or streamlink) */
+func (stream *Stream) failback_probe() {
+	if stream.probe_status != nil { return }     // probe still running
+	config  := stream.player_cfg
+	primary := config.playlist.Locations[stream.playlist_pos]
+	options := cmd.Options{ Buffered : true }
+	probe   := config.ytdl_cmd.new_cmd(options, "--simulate", "--quiet", primary)
+	if config.use_streamlink {
+		probe = config.streamlink_cmd.new_cmd(options, "--json", primary)
+	}
+	stream.probe_cmd    = probe
+	stream.probe_status = probe.Start()
+}
+
+func (stream *Stream) failback_probed(status *cmd.Status) {
+	stream.probe_cmd    = nil
+	stream.probe_status = nil
+	if (stream.source_idx == 0) || (status.Exit != 0) { return }
+	stream.log.Info("primary location healthy again")
+	stream.source_select(0)
+	if (stream.state != ST_Stopped) && (stream.target_state == UR_Play) {
+		stream.request_state("restart")
+	}
+}
@@ -1,13 +1,18 @@
 module github.com/znuh/fnordstream
 
-go 1.18
+go 1.21
 
 require (
 	github.com/go-cmd/cmd v1.4.1
 	github.com/gorilla/websocket v1.5.0
 	github.com/mitchellh/mapstructure v1.5.0
 	github.com/netdata/go.d.plugin v0.49.2
+	github.com/robfig/cron/v3 v3.0.1
+	golang.org/x/term v0.20.0
 	gopkg.in/natefinch/npipe.v2 v2.0.0-20160621034901-c1b8fa8bdcce
 )
 
-require github.com/apparentlymart/go-cidr v1.1.0 // indirect
+require (
+	github.com/apparentlymart/go-cidr v1.1.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+)
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"strings"
+	"log/slog"
+	"sync/atomic"
+)
+
+/* global log level - can be changed at runtime through the log_ctl request */
+var log_level = new(slog.LevelVar)
+
+/* stream state machine debug output (see Stream.debug()) - runtime toggle */
+var stream_debug atomic.Bool
+
+func parse_log_level(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.ToUpper(s)))
+	return level, err
+}
+
+/* log destination if no log file is given (TUI mode redirects this) */
+var log_output io.Writer = os.Stderr
+
+/* setup default logger - log to log_output or to file (if fname is given) */
+func log_setup(level string, fname string) error {
+	lvl, err := parse_log_level(level)
+	if err != nil { return err }
+	log_level.Set(lvl)
+
+	out := log_output
+	if fname != "" {
+		fh, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil { return err }
+		out = fh
+	}
+	handler := slog.NewTextHandler(out, &slog.HandlerOptions{ Level : log_level })
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+/* logger for a subsystem (hub, webif, displays, stream[N], ...) */
+func logger(subsys string) *slog.Logger {
+	return slog.Default().With("subsys", subsys)
+}
+
+func stream_logger(stream_id int) *slog.Logger {
+	return logger(fmt.Sprintf("stream[%d]", stream_id))
+}
+
+/* log error and terminate */
+func log_fatal(log *slog.Logger, msg string, args ...any) {
+	log.Error(msg, args...)
+	os.Exit(1)
+}
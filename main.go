@@ -1,8 +1,14 @@
 package main
 
 import (
+	"os"
 	"flag"
 	"fmt"
+	"time"
+	"syscall"
+	"os/signal"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 /* TODOs:
@@ -12,21 +18,138 @@ import (
  * - go: OSX monitor detection
  * - layout customisation
  */
+/* max. time to wait for players on shutdown */
+const shutdown_timeout = 10 * time.Second
+
+/* SIGINT/SIGTERM: stop all players, then shut down web UI (main() returns) or exit
+ * handler is installed on return */
+func shutdown_on_signal(shub *StreamHub, webif_stop chan struct{}, no_web bool) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		signal.Stop(sigs)         // 2nd signal terminates immediately
+		tui_restore()             // leave raw mode before exiting (no-op w/o -tui)
+		logger("main").Info("shutting down", "signal", sig.String())
+		sd_notify("STOPPING=1")
+		shub.Shutdown(shutdown_timeout)
+		if no_web { os.Exit(0) }
+		close(webif_stop)
+	}()
+}
+
+/* SIGHUP: reload config file */
+func reload_on_signal(shub *StreamHub) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	for range sigs {
+		logger("main").Info("reloading config", "signal", "hangup")
+		shub.client_requests <- &ClientRequest{ request : map[string]interface{}{ "request" : "reload_config" } }
+	}
+}
+
 func main() {
-	fmt.Println("fnordstream v"+version_info)
-	no_web          := flag.Bool("no-web", false, "disable webui")
-	listen_addr     := flag.String("listen-addr", "localhost:8090", "listen address for web UI")
-	webui_acl       := flag.String("allowed-ips", "<ANY>", "allowed IPs for web UI (ranges/netmasks allowed, separate multiple with a comma)")
-	allowed_origins := flag.String("allowed-origins", "", "allowed Origins for secondary mode operation (separate multiple with a comma)")
+	/* flag defaults from default config - flags given on the command line override the config file */
+	defaults := default_config()
+	flag.Bool("no-web", defaults.No_web, "disable webui")
+	flag.String("listen-addr", defaults.Listen_addr, "listen address for web UI")
+	flag.String("allowed-ips", defaults.Allowed_ips, "allowed IPs for web UI (ranges/netmasks allowed, separate multiple with a comma)")
+	flag.String("allowed-origins", defaults.Allowed_origins, "allowed Origins for secondary mode operation (separate multiple with a comma)")
+	flag.String("log-level", defaults.Log_level, "log level (debug, info, warn, error)")
+	flag.String("log-file", defaults.Log_file, "write log to file instead of stderr")
+	flag.Bool("stream-debug", defaults.Stream_debug, "log stream state machine transitions (needs -log-level=debug)")
+	flag.String("record-dir", defaults.Record_dir, "output directory for stream recordings")
+	flag.String("record-template", defaults.Record_template, "filename template for recordings ({id}, {date}, {time}, {location})")
+	flag.String("web-dir", defaults.Web_dir, "web UI directory (if webfs is not embedded)")
+	flag.String("pid-file", defaults.Pid_file, "write process ID to this file")
+	flag.String("autostart", defaults.Autostart, "start this stored profile on startup")
+	flag.Bool("resume", defaults.Resume, "save the running session and resume it on startup (preferred over -autostart)")
+	tui_mode        := flag.Bool("tui", false, "full-screen terminal dashboard")
+	daemon          := flag.Bool("daemon", false, "service mode - no console/TUI, keep running w/o web UI until stopped")
+	work_dir        := flag.String("work-dir", "", "change to this working directory on startup")
+	config_dir_flag := flag.String("config-dir", "", "config directory (default: per-user config directory)")
+	config_fname    := flag.String("config", "", "config file (default: " + config_file + " in config directory)")
 	flag.Parse()
 
-	shub := NewStreamHub()
+	if *work_dir != "" {
+		if err := os.Chdir(*work_dir); err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: cannot change working directory:", err)
+			os.Exit(1)
+		}
+	}
+	config_dir = *config_dir_flag
+
+	config_required := *config_fname != ""
+	if !config_required { *config_fname = config_path(config_file) }
+	overrides   := config_flag_overrides()
+	config, err := config_load(*config_fname, config_required, overrides)
+
+	/* command-line client for a running instance - works w/ broken config (e.g. to fix & reload it) */
+	if (len(flag.Args()) > 0) && (flag.Args()[0] == "ctl") {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "WARNING: cannot load config:", err)
+			config = default_config()
+			mapstructure.Decode(overrides, config)
+		}
+		os.Exit(ctl_main(config.Listen_addr, flag.Args()[1:]))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR: cannot load config:", err)
+		os.Exit(1)
+	}
+	fmt.Println("fnordstream v"+version_info)
+
+	if *daemon && (*tui_mode || (len(flag.Args()) > 0)) {
+		fmt.Fprintln(os.Stderr, "ERROR: -daemon cannot be combined with -tui or a stream list")
+		os.Exit(2)
+	}
+
+	tui_log := make(TuiLog, 64)
+	if *tui_mode {
+		log_output = tui_log
+	}
+	if err := log_setup(config.Log_level, config.Log_file); err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR: logging setup failed:", err)
+		os.Exit(1)
+	}
+
+	no_web := config.No_web
+	shub   := NewStreamHub(config)
+	shub.config_file, shub.config_required, shub.config_overrides = *config_fname, config_required, overrides
+	if config.Pid_file != "" {
+		if err := pid_file_write(config.Pid_file); err != nil {
+			log_fatal(shub.log, "cannot write PID file", "err", err)
+		}
+		shub.pid_file = config.Pid_file
+	}
 	go shub.Run()
+	webif_stop := make(chan struct{})
+	shutdown_on_signal(shub, webif_stop, no_web)    // before console/TUI - they block w/o web UI
+	go reload_on_signal(shub)
+	service_autostart(shub, config.Autostart, config.Resume)
 
 	if len(flag.Args()) > 0 {
-		console_client(shub, flag.Args()[0], *no_web)
+		specname    := flag.Args()[0]
+		interactive := (!*tui_mode) && (specname != "-") && console_interactive()
+		console_client(shub, specname, no_web && !*tui_mode, interactive)
 	}
-	if !(*no_web) {
-		webif_run(shub, *listen_addr, *webui_acl, *allowed_origins)
+	if *tui_mode {
+		tui := func() {
+			tui_run(shub, tui_log)
+			shub.Shutdown(shutdown_timeout)
+			os.Exit(0)
+		}
+		if no_web { tui() }
+		go tui()
+	}
+	if *daemon && no_web {      // run until stopped (see shutdown_on_signal)
+		service_ready(shub)
+		select {}
+	}
+
+	if !no_web {
+		webif_run(shub, config.Listen_addr, config.Allowed_ips, config.Allowed_origins, config.Web_dir, webif_stop)
+		return
 	}
+	shub.Shutdown(shutdown_timeout)    // console done - remove runtime directories
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"sort"
+	"strings"
+	"net/http"
+)
+
+/* per-stream metrics - updated by notification handlers in StreamHub.Run() */
+type StreamMetrics struct {
+	starts                 int
+	restarts               int
+	exit_code             *int
+	stalls                 int
+
+	paused_for_cache       bool
+	paused_since           time.Time
+	paused_total           time.Duration
+}
+
+func (sm *StreamMetrics) player_status(status *PlayerStatus) {
+	if status.Exit_code != nil {
+		exit_code   := *status.Exit_code
+		sm.exit_code = &exit_code
+	}
+	if status.Status == "starting" {
+		if sm.starts > 0 { sm.restarts++ }
+		sm.starts++
+	} else if status.Status == "stalled" {
+		sm.stalls++
+	}
+	if (status.Status == "stopped") || (status.Status == "starting") {
+		sm.set_paused_for_cache(false)
+	}
+}
+
+func (sm *StreamMetrics) set_paused_for_cache(paused bool) {
+	if paused == sm.paused_for_cache { return }
+	now := time.Now()
+	if paused {
+		sm.paused_since  = now
+	} else {
+		sm.paused_total += now.Sub(sm.paused_since)
+	}
+	sm.paused_for_cache = paused
+}
+
+func (sm *StreamMetrics) paused_seconds() float64 {
+	res := sm.paused_total
+	if sm.paused_for_cache {
+		res += time.Since(sm.paused_since)
+	}
+	return res.Seconds()
+}
+
+var player_states = [...]string{ "stopped", "starting", "playing", "stalled", "stopping", "restarting" }
+
+var label_escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metrics_writer struct {
+	sb     strings.Builder
+}
+
+func (mw *metrics_writer) header(name string, mtype string, help string) {
+	fmt.Fprintf(&mw.sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, mtype)
+}
+
+func (mw *metrics_writer) value(name string, labels string, val float64) {
+	if labels != "" { labels = "{" + labels + "}" }
+	fmt.Fprintf(&mw.sb, "%s%s %g\n", name, labels, val)
+}
+
+/* render metrics in Prometheus text exposition format
+ * must be called from StreamHub.Run() context */
+func (hub *StreamHub) metrics_text() []byte {
+	mw := &metrics_writer{}
+
+	mw.header("fnordstream_clients", "gauge", "Number of connected clients.")
+	mw.value("fnordstream_clients", "", float64(len(hub.clients)))
+
+	mw.header("fnordstream_notifications_dropped_total", "counter", "Notifications dropped due to full client buffers.")
+	mw.value("fnordstream_notifications_dropped_total", "", float64(hub.dropped_notifications))
+
+	mw.header("fnordstream_slow_client_disconnects_total", "counter", "Clients disconnected for lagging too long.")
+	mw.value("fnordstream_slow_client_disconnects_total", "", float64(hub.slow_disconnects))
+
+	clients := make([]*Client, 0, len(hub.clients))
+	for client := range hub.clients { clients = append(clients, client) }
+	sort.Slice(clients, func(i, j int) bool { return clients[i].remote < clients[j].remote })
+	client_labels := func(client *Client) string {
+		return fmt.Sprintf(`remote="%s"`, label_escaper.Replace(client.remote))
+	}
+	mw.header("fnordstream_client_notifications_dropped_total", "counter", "Notifications dropped per connected client.")
+	for _, client := range clients {
+		mw.value("fnordstream_client_notifications_dropped_total", client_labels(client), float64(client.dropped))
+	}
+	mw.header("fnordstream_client_resyncs_total", "counter", "Status snapshots sent to a client after dropped notifications.")
+	for _, client := range clients {
+		mw.value("fnordstream_client_resyncs_total", client_labels(client), float64(client.resyncs))
+	}
+	mw.header("fnordstream_client_queue_length", "gauge", "Notifications queued for a client.")
+	for _, client := range clients {
+		if client.client_notify == nil { continue }
+		mw.value("fnordstream_client_queue_length", client_labels(client), float64(len(client.client_notify)))
+	}
+
+	playing := 0.0
+	if hub.streams_playing { playing = 1.0 }
+	mw.header("fnordstream_streams_playing", "gauge", "Whether playback mode is active.")
+	mw.value("fnordstream_streams_playing", "", playing)
+
+	if !hub.streams_playing { return []byte(mw.sb.String()) }
+
+	labels := make([]string, len(hub.stream_status))
+	for idx, status := range hub.stream_status {
+		if status == nil { continue }
+		labels[idx] = fmt.Sprintf(`stream_id="%d",location="%s"`, idx, label_escaper.Replace(status.Location))
+	}
+
+	mw.header("fnordstream_player_status", "gauge", "Current player status (1 for the active status).")
+	for idx, status := range hub.stream_status {
+		if status == nil { continue }
+		for _, st := range player_states {
+			val := 0.0
+			if status.Player_status == st { val = 1.0 }
+			mw.value("fnordstream_player_status", labels[idx]+`,status="`+st+`"`, val)
+		}
+	}
+
+	mw.header("fnordstream_player_starts_total", "counter", "Player starts.")
+	for idx, sm := range hub.stream_metrics {
+		mw.value("fnordstream_player_starts_total", labels[idx], float64(sm.starts))
+	}
+
+	mw.header("fnordstream_player_restarts_total", "counter", "Player restarts.")
+	for idx, sm := range hub.stream_metrics {
+		mw.value("fnordstream_player_restarts_total", labels[idx], float64(sm.restarts))
+	}
+
+	mw.header("fnordstream_player_stalls_total", "counter", "Stalls detected by the player watchdog.")
+	for idx, sm := range hub.stream_metrics {
+		mw.value("fnordstream_player_stalls_total", labels[idx], float64(sm.stalls))
+	}
+
+	mw.header("fnordstream_player_exit_code", "gauge", "Exit code of the last player run.")
+	for idx, sm := range hub.stream_metrics {
+		if sm.exit_code == nil { continue }
+		mw.value("fnordstream_player_exit_code", labels[idx], float64(*sm.exit_code))
+	}
+
+	mw.header("fnordstream_player_paused_for_cache_seconds_total", "counter", "Time spent in paused-for-cache state.")
+	for idx, sm := range hub.stream_metrics {
+		mw.value("fnordstream_player_paused_for_cache_seconds_total", labels[idx], sm.paused_seconds())
+	}
+
+	/* numeric player properties */
+	properties := map[string]string{
+		"demuxer-cache-duration" : "fnordstream_player_demuxer_cache_duration_seconds",
+		"video-bitrate"          : "fnordstream_player_video_bitrate_bits_per_second",
+	}
+	props := make([]string, 0, len(properties))
+	for prop := range properties { props = append(props, prop) }
+	sort.Strings(props)
+
+	for _, prop := range props {
+		name := properties[prop]
+		mw.header(name, "gauge", "mpv property "+prop+".")
+		for idx, status := range hub.stream_status {
+			if status == nil { continue }
+			val, ok := status.Properties[prop].(float64)
+			if !ok { continue }
+			mw.value(name, labels[idx], val)
+		}
+	}
+
+	return []byte(mw.sb.String())
+}
+
+/* HTTP handler - metrics are rendered in StreamHub.Run() context */
+func metrics_handler(shub *StreamHub) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		reply := make(chan []byte, 1)
+		shub.metrics_req <- reply
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(<-reply)
+	})
+}
@@ -32,6 +32,7 @@ func player_status_update(hub *StreamHub, note *Notification) {
 	if !ok { return }
 
 	stream_status.Player_status = status.Status
+	hub.stream_metrics[idx].player_status(status)
 
 	/* delete old properties */
 	if status.Status == "stopped" {
@@ -55,13 +56,71 @@ func player_event(hub *StreamHub, note *Notification) {
 
 	if evt.Event == "property-change" {
 		stream_status.Properties[evt.Name] = evt.Data
+		if evt.Name == "paused-for-cache" {
+			paused, _ := evt.Data.(bool)
+			hub.stream_metrics[idx].set_paused_for_cache(paused)
+		}
 	}
 }
 
+func player_log(hub *StreamHub, note *Notification) {
+	idx := note.stream_id
+	if (idx < 0) || (idx >= len(hub.stream_logs)) { return }
+
+	line, ok := note.payload.(*PlayerLogLine)
+	if !ok { return }
+	hub.stream_logs[idx].Add(line)
+}
+
+func thumbnail_updated(hub *StreamHub, note *Notification) {
+	idx := note.stream_id
+	if (idx < 0) || (idx >= len(hub.stream_status)) { return }
+
+	info, ok := note.payload.(*ThumbnailInfo)
+	if !ok || (hub.stream_status[idx] == nil) { return }
+	hub.stream_status[idx].Thumbnail = info
+}
+
+func recording_status(hub *StreamHub, note *Notification) {
+	idx := note.stream_id
+	if (idx < 0) || (idx >= len(hub.stream_status)) { return }
+
+	status, ok := note.payload.(*RecordingStatus)
+	if !ok || (hub.stream_status[idx] == nil) { return }
+	if !status.Recording { status = nil }
+	hub.stream_status[idx].Recording = status
+}
+
+func playlist_status(hub *StreamHub, note *Notification) {
+	idx := note.stream_id
+	if (idx < 0) || (idx >= len(hub.stream_status)) { return }
+
+	status, ok := note.payload.(*PlaylistStatus)
+	if !ok || (hub.stream_status[idx] == nil) { return }
+	hub.stream_status[idx].Location     = status.Location
+	hub.stream_status[idx].Playlist_pos = status.Pos
+	hub.stream_status[idx].Source       = 0
+}
+
+func source_status(hub *StreamHub, note *Notification) {
+	idx := note.stream_id
+	if (idx < 0) || (idx >= len(hub.stream_status)) { return }
+
+	status, ok := note.payload.(*SourceStatus)
+	if !ok || (hub.stream_status[idx] == nil) { return }
+	hub.stream_status[idx].Location = status.Location
+	hub.stream_status[idx].Source   = status.Source
+}
+
 var note_handlers = map[string]NotificationHandler{
-	"displays"      : displays_update,
-	"player_status" : player_status_update,
-	"player_event"  : player_event,
+	"displays"          : displays_update,
+	"player_status"     : player_status_update,
+	"player_event"      : player_event,
+	"player_log"        : player_log,
+	"thumbnail_updated" : thumbnail_updated,
+	"recording_status"  : recording_status,
+	"playlist_status"   : playlist_status,
+	"source_status"     : source_status,
 }
 
 func notification(hub *StreamHub, note *Notification) {
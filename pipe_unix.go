@@ -2,8 +2,66 @@
 
 package main
 
-import "net"
+import (
+	"os"
+	"net"
+	"time"
+	"errors"
+	"strconv"
+	"strings"
+	"syscall"
+	"path/filepath"
+)
+
+const pipe_dir_prefix = "fnordstream-"
 
 func dial_pipe(path string) (net.Conn, error) {
 	return net.Dial("unix", path)
 }
+
+/* remove leftover IPC socket (mpv doesn't remove it when killed) */
+func remove_pipe(path string) {
+	fi, err := os.Lstat(path)
+	if (err != nil) || (fi.Mode() & os.ModeSocket == 0) { return }
+	os.Remove(path)
+}
+
+/* remove socket before player start if nobody listens on it anymore */
+func remove_stale_pipe(path string) error {
+	if _, err := os.Lstat(path); err != nil { return nil }
+	conn, err := net.DialTimeout("unix", path, 100*time.Millisecond)
+	if err == nil {
+		conn.Close()
+		return errors.New("IPC socket in use by another player")
+	}
+	remove_pipe(path)
+	return nil
+}
+
+/* per-instance socket directory (mode 0700) in base, $XDG_RUNTIME_DIR or the temp dir
+ * name contains the PID so directories of dead instances can be removed
+ * returns pipe name prefix and directory */
+func pipe_setup(base string) (string, string, error) {
+	if base == "" { base = os.Getenv("XDG_RUNTIME_DIR") }
+	if base == "" { base = os.TempDir() }
+	pipe_cleanup(base)
+	dir, err := os.MkdirTemp(base, pipe_dir_prefix + strconv.Itoa(os.Getpid()) + "-")
+	if err != nil { return "", "", err }
+	return filepath.Join(dir, "mpv_ipc"), dir, nil
+}
+
+/* remove own socket directories of instances that are gone */
+func pipe_cleanup(base string) {
+	entries, _ := os.ReadDir(base)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, pipe_dir_prefix) { continue }
+		pid, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(name, pipe_dir_prefix), "-", 2)[0])
+		if (err != nil) || (syscall.Kill(pid, 0) != syscall.ESRCH) { continue }    // alive or not ours to probe
+		info, err := entry.Info()
+		if err != nil { continue }
+		if st, ok := info.Sys().(*syscall.Stat_t); !ok || (int(st.Uid) != os.Getuid()) { continue }
+		logger("hub").Info("removing stale IPC directory", "dir", name)
+		os.RemoveAll(filepath.Join(base, name))
+	}
+}
@@ -3,11 +3,25 @@
 package main
 
 import (
+	"os"
 	"net"
 	"time"
+	"strconv"
+	"math/rand"
 	"gopkg.in/natefinch/npipe.v2"
 )
 
 func dial_pipe(path string) (net.Conn, error) {
 	return npipe.DialTimeout(path,time.Duration(time.Millisecond * 100))
 }
+
+/* named pipes vanish with the player - nothing to clean up */
+func remove_pipe(path string) {}
+
+func remove_stale_pipe(path string) error { return nil }
+
+/* per-instance pipe names (PID + random token) - no directory on windows (base is ignored) */
+func pipe_setup(base string) (string, string, error) {
+	token := strconv.FormatUint(rand.Uint64(), 36)
+	return "\\\\.\\pipe\\fnordstream-" + strconv.Itoa(os.Getpid()) + "-" + token + "-mpv_ipc", "", nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"time"
+)
+
+const player_log_lines = 200
+
+type PlayerLogLine struct {
+	Ts                  time.Time   `json:"ts"`
+	Src                 string      `json:"src"`     // stdout or stderr
+	Line                string      `json:"line"`
+}
+
+/* fixed size ring buffer holding the most recent output lines of a player
+ * only accessed from StreamHub.Run() context - no locking needed */
+type PlayerLog struct {
+	lines             []*PlayerLogLine
+	next                int
+	full                bool
+}
+
+func NewPlayerLog(size int) *PlayerLog {
+	return &PlayerLog{ lines : make([]*PlayerLogLine, size) }
+}
+
+func (pl *PlayerLog) Add(line *PlayerLogLine) {
+	pl.lines[pl.next] = line
+	pl.next++
+	if pl.next >= len(pl.lines) {
+		pl.next = 0
+		pl.full = true
+	}
+}
+
+/* returns buffered lines, oldest first */
+func (pl *PlayerLog) Lines() []*PlayerLogLine {
+	if !pl.full {
+		return append([]*PlayerLogLine{}, pl.lines[:pl.next]...)
+	}
+	res := make([]*PlayerLogLine, 0, len(pl.lines))
+	res  = append(res, pl.lines[pl.next:]...)
+	return append(res, pl.lines[:pl.next]...)
+}
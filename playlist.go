@@ -0,0 +1,153 @@
+package main
+
+import (
+	"time"
+	"regexp"
+	"strings"
+	"unicode"
+	"encoding/json"
+	"github.com/go-cmd/cmd"
+	"github.com/mitchellh/mapstructure"
+)
+
+/* delay before advancing to the next playlist item after a player error */
+const playlist_error_delay = 1 * time.Second
+
+/* playlist/rotation settings of a viewport
+ * start_streams accepts either a plain location or an object:
+ * {"locations":[...], "rotate_interval":300, "advance_on_eof":true, "advance_on_error":true,
+ *  "backups":[...], "failover_errors":3, "failback_interval":60,
+ *  "title":"label", "options":{"user-agent":"...", "referrer":"..."}}
+ * backups are mirrors used when the current location keeps failing (see failover.go)
+ * title and options are usually taken from imported playlists (see playlist_import.go) */
+type PlaylistConfig struct {
+	Locations           []string    `json:"locations" mapstructure:"locations"`
+	Rotate_interval     float64     `json:"rotate_interval,omitempty" mapstructure:"rotate_interval"`  // seconds (0: off)
+	Advance_on_eof      bool        `json:"advance_on_eof,omitempty" mapstructure:"advance_on_eof"`
+	Advance_on_error    bool        `json:"advance_on_error,omitempty" mapstructure:"advance_on_error"`
+
+	Backups             []string    `json:"backups,omitempty" mapstructure:"backups"`
+	Failover_errors     int         `json:"failover_errors,omitempty" mapstructure:"failover_errors"`      // 0: default
+	Failback_interval   float64     `json:"failback_interval,omitempty" mapstructure:"failback_interval"`  // seconds (0: default)
+
+	Title               string             `json:"title,omitempty" mapstructure:"title"`       // display label
+	Options             map[string]string  `json:"options,omitempty" mapstructure:"options"`   // see entry_options
+}
+
+/* per-entry player options: option -> mpv option, streamlink HTTP header */
+var entry_options = map[string][2]string{
+	"user-agent" : {"--user-agent", "User-Agent"},
+	"referrer"   : {"--referrer",   "Referer"},
+}
+
+func printable(str string) bool {
+	for _, r := range str {
+		if !unicode.IsPrint(r) { return false }
+	}
+	return true
+}
+
+type PlaylistStatus struct {
+	Pos                 int         `json:"pos"`
+	Location            string      `json:"location"`
+}
+
+/* parse & sanitize one entry of the start_streams stream list */
+func parse_stream_entry(entry interface{}, sanitize *regexp.Regexp) (*PlaylistConfig, bool) {
+	res := &PlaylistConfig{}
+	switch v := entry.(type) {
+		case string:
+			res.Locations = []string{v}
+		case map[string]interface{}:
+			if err := mapstructure.Decode(v, res); err != nil { return nil, false }
+		default:
+			return nil, false
+	}
+	for _, list := range [][]string{res.Locations, res.Backups} {
+		for idx, location := range list {
+			location = strings.TrimSpace(sanitize.ReplaceAllString(location, ""))
+			if len(location) < 1 { return nil, false }
+			list[idx] = location
+		}
+	}
+	if len(res.Locations) < 1 { return nil, false }
+	if (len(res.Title) > 128) || !printable(res.Title) { return nil, false }
+	for name, val := range res.Options {
+		if _, ok := entry_options[name]; !ok || (len(val) > 512) || !printable(val) { return nil, false }
+	}
+	return res, true
+}
+
+/* player args for the entry options */
+func (config *PlayerConfig) entry_args() (mpv_args []string, streamlink_args []string) {
+	for name, val := range config.playlist.Options {
+		opt := entry_options[name]
+		mpv_args        = append(mpv_args, opt[0] + "=" + val)
+		streamlink_args = append(streamlink_args, "--http-header", opt[1] + "=" + val)
+	}
+	return
+}
+
+func (stream *Stream) playlist_len() int {
+	return len(stream.player_cfg.playlist.Locations)
+}
+
+/* rotation timer - started from NewStream() */
+func (stream *Stream) playlist_start() {
+	interval := stream.player_cfg.playlist.Rotate_interval
+	if (interval <= 0) || (stream.playlist_len() < 2) { return }
+	stream.rotate_ticker    = time.NewTicker(time.Duration(interval * float64(time.Second)))
+	stream.rotate_ticker_ch = stream.rotate_ticker.C
+}
+
+/* switch to playlist item - takes effect on next player start */
+func (stream *Stream) playlist_select(pos int) {
+	n := stream.playlist_len()
+	if n < 1 { return }
+	pos = ((pos % n) + n) % n
+	stream.playlist_pos        = pos
+	stream.player_cfg.location = stream.player_cfg.playlist.Locations[pos]
+
+	// new item - start over with its primary location
+	stream.source_idx  = 0
+	stream.error_count = 0
+	stream.failback_stop()
+
+	status      := &PlaylistStatus{ Pos : pos, Location : stream.player_cfg.location }
+	json_msg, _ := json.Marshal(status)
+	stream.notifications <- &Notification{
+		stream_id    : stream.stream_id,
+		notification : "playlist_status",
+		payload      : status,
+		json_message : json_msg,
+	}
+}
+
+/* next/previous item - restarts a running player */
+func (stream *Stream) playlist_ctl(val string) {
+	if stream.playlist_len() < 2 { return }
+	switch val {
+		case "next"     : stream.playlist_select(stream.playlist_pos + 1)
+		case "previous" : stream.playlist_select(stream.playlist_pos - 1)
+		default         : return
+	}
+	if (stream.state != ST_Stopped) && (stream.target_state == UR_Play) {
+		stream.request_state("restart")
+	}
+}
+
+/* called from schedule_restart() when a playing player exited
+ * returns restart delay if the playlist advanced, -1 otherwise */
+func (stream *Stream) playlist_advance(cmd_status *cmd.Status) time.Duration {
+	playlist := &stream.player_cfg.playlist
+	if stream.playlist_len() < 2 { return -1 }
+	if (cmd_status.Exit == 0) && playlist.Advance_on_eof {
+		stream.playlist_select(stream.playlist_pos + 1)
+		return 0
+	} else if (cmd_status.Exit > 0) && (cmd_status.Exit < 127) && playlist.Advance_on_error {
+		if !stream.failover_exhausted() { return -1 }      // try backups first (see failover())
+		stream.playlist_select(stream.playlist_pos + 1)
+		return playlist_error_delay
+	}
+	return -1
+}
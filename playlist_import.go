@@ -0,0 +1,113 @@
+package main
+
+import (
+	"sort"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/* import of M3U/extended M3U and PLS playlists
+ * each playlist item becomes one stream entry (see parse_stream_entry) -
+ * a plain location or {"locations":[...], "title":..., "options":{...}} if it has a title/options */
+
+/* #EXTVLCOPT options we can pass on to the player (VLC name -> stream entry option) */
+var import_vlc_options = map[string]string{
+	"http-user-agent" : "user-agent",
+	"http-referrer"   : "referrer",
+}
+
+/* m3u, pls or "" (no playlist - plain list of locations) */
+func playlist_format(text string) string {
+	text = strings.TrimLeftFunc(strings.TrimPrefix(text, "\ufeff"), unicode.IsSpace)
+	if strings.HasPrefix(text, "#EXTM3U") { return "m3u" }
+	if strings.HasPrefix(strings.ToLower(text), "[playlist]") { return "pls" }
+	return ""
+}
+
+func import_playlist_text(text string) ([]interface{}, error) {
+	var res []interface{}
+	switch playlist_format(text) {
+		case "m3u" : res = parse_m3u(text)
+		case "pls" : res = parse_pls(text)
+		default    : return nil, errors.New("unknown playlist format (M3U or PLS expected)")
+	}
+	if len(res) < 1 { return nil, errors.New("playlist has no entries") }
+	return res, nil
+}
+
+func import_entry(location string, title string, options map[string]string) interface{} {
+	if (title == "") && (len(options) < 1) { return location }
+	entry := map[string]interface{}{ "locations" : []interface{}{location} }
+	if title != "" { entry["title"] = title }
+	if len(options) > 0 {
+		opts := map[string]interface{}{}
+		for k, v := range options { opts[k] = v }
+		entry["options"] = opts
+	}
+	return entry
+}
+
+/* title of an #EXTINF line: text after the first comma outside of quoted attributes
+ * #EXTINF:-1 tvg-id="x" group-title="a, b",Title */
+func m3u_title(info string) string {
+	quoted := false
+	for idx, r := range info {
+		switch {
+			case r == '"'                : quoted = !quoted
+			case (r == ',') && !quoted   : return strings.TrimSpace(info[idx+1:])
+		}
+	}
+	return ""
+}
+
+func parse_m3u(text string) []interface{} {
+	res     := []interface{}{}
+	title   := ""
+	options := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+		switch {
+			case line == "":
+			case strings.HasPrefix(line, "#EXTINF:"):
+				title = m3u_title(line[len("#EXTINF:"):])
+			case strings.HasPrefix(line, "#EXTVLCOPT:"):
+				kv := strings.SplitN(line[len("#EXTVLCOPT:"):], "=", 2)
+				if name, ok := import_vlc_options[strings.TrimSpace(kv[0])]; ok && (len(kv) == 2) {
+					options[name] = strings.TrimSpace(kv[1])
+				}
+			case strings.HasPrefix(line, "#"):           // other directives/comments
+			default:
+				res     = append(res, import_entry(line, title, options))
+				title   = ""
+				options = map[string]string{}
+		}
+	}
+	return res
+}
+
+/* [playlist] FileN=..., TitleN=... - entries ordered by N */
+func parse_pls(text string) []interface{} {
+	files  := map[int]string{}
+	titles := map[int]string{}
+	for _, line := range strings.Split(text, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) < 2 { continue }
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		val := strings.TrimSpace(kv[1])
+		for prefix, dst := range map[string]map[int]string{"file" : files, "title" : titles} {
+			if !strings.HasPrefix(key, prefix) { continue }
+			if n, err := strconv.Atoi(key[len(prefix):]); err == nil { dst[n] = val }
+		}
+	}
+	idx := make([]int, 0, len(files))
+	for n := range files { idx = append(idx, n) }
+	sort.Ints(idx)
+	res := []interface{}{}
+	for _, n := range idx {
+		if files[n] == "" { continue }
+		res = append(res, import_entry(files[n], titles[n], nil))
+	}
+	return res
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlaylistFormat(t *testing.T) {
+	tests := []struct {
+		text        string
+		format      string
+	}{
+		{ "#EXTM3U\nhttp://a\n",          "m3u" },
+		{ "\ufeff#EXTM3U\n",           "m3u" },
+		{ "\n  #EXTM3U\n",                "m3u" },
+		{ "[playlist]\nFile1=http://a\n", "pls" },
+		{ "[Playlist]\n",                 "pls" },
+		{ "http://a\nhttp://b\n",         "" },
+		{ "",                             "" },
+	}
+	for _, test := range tests {
+		if format := playlist_format(test.text); format != test.format {
+			t.Errorf("playlist_format(%q) = %q, want %q", test.text, format, test.format)
+		}
+	}
+}
+
+func TestM3uTitle(t *testing.T) {
+	tests := []struct {
+		info        string
+		title       string
+	}{
+		{ "-1,Title",                                      "Title" },
+		{ "-1, Title with spaces ",                        "Title with spaces" },
+		{ `-1 tvg-id="x" group-title="a, b",Title`,        "Title" },
+		{ "-1,Title, with comma",                          "Title, with comma" },
+		{ "-1",                                            "" },
+		{ `-1 group-title="unterminated, x`,               "" },
+	}
+	for _, test := range tests {
+		if title := m3u_title(test.info); title != test.title {
+			t.Errorf("m3u_title(%q) = %q, want %q", test.info, title, test.title)
+		}
+	}
+}
+
+func TestParseM3u(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		entries   []interface{}
+	}{
+		{ "plain", "#EXTM3U\nhttp://a\n\nhttp://b\n", []interface{}{"http://a", "http://b"} },
+		{ "crlf & bom", "\ufeff#EXTM3U\r\nhttp://a\r\n", []interface{}{"http://a"} },
+		{ "comments & directives", "#EXTM3U\n# comment\n#EXT-X-VERSION:3\nhttp://a\n", []interface{}{"http://a"} },
+		{ "relative entries are kept", "#EXTM3U\nvideo/a.mp4\n../b.ts\n", []interface{}{"video/a.mp4", "../b.ts"} },
+		{ "title", "#EXTM3U\n#EXTINF:-1,Cam 1\nhttp://a\nhttp://b\n", []interface{}{
+			map[string]interface{}{ "locations" : []interface{}{"http://a"}, "title" : "Cam 1" },
+			"http://b",
+		} },
+		{ "vlc options", "#EXTM3U\n#EXTVLCOPT:http-user-agent=UA 1\n#EXTVLCOPT:http-referrer=http://r\n#EXTVLCOPT:network-caching=1000\nhttp://a\n", []interface{}{
+			map[string]interface{}{
+				"locations" : []interface{}{"http://a"},
+				"options"   : map[string]interface{}{ "user-agent" : "UA 1", "referrer" : "http://r" },
+			},
+		} },
+		{ "no entries", "#EXTM3U\n#EXTINF:-1,dangling\n", []interface{}{} },
+	}
+	for _, test := range tests {
+		if entries := parse_m3u(test.text); !reflect.DeepEqual(entries, test.entries) {
+			t.Errorf("parse_m3u %s: got %#v, want %#v", test.name, entries, test.entries)
+		}
+	}
+}
+
+func TestParsePls(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		entries   []interface{}
+	}{
+		{ "ordered by number", "[playlist]\nFile2=http://b\nFile1=http://a\nFile10=http://c\nNumberOfEntries=3\n",
+			[]interface{}{"http://a", "http://b", "http://c"} },
+		{ "titles & case", "[playlist]\r\nfile1 = http://a \r\nTitle1=Cam 1\r\nLength1=-1\r\n", []interface{}{
+			map[string]interface{}{ "locations" : []interface{}{"http://a"}, "title" : "Cam 1" },
+		} },
+		{ "value w/ =", "[playlist]\nFile1=http://a/?x=1&y=2\n", []interface{}{"http://a/?x=1&y=2"} },
+		{ "empty & invalid keys", "[playlist]\nFile1=\nFileX=http://x\nFile2=http://b\n", []interface{}{"http://b"} },
+		{ "no entries", "[playlist]\nNumberOfEntries=0\n", []interface{}{} },
+	}
+	for _, test := range tests {
+		if entries := parse_pls(test.text); !reflect.DeepEqual(entries, test.entries) {
+			t.Errorf("parse_pls %s: got %#v, want %#v", test.name, entries, test.entries)
+		}
+	}
+}
+
+func TestImportPlaylistText(t *testing.T) {
+	if _, err := import_playlist_text("http://a\n"); err == nil {
+		t.Error("plain list: error expected")
+	}
+	if _, err := import_playlist_text("#EXTM3U\n"); err == nil {
+		t.Error("empty playlist: error expected")
+	}
+	if entries, err := import_playlist_text("[playlist]\nFile1=http://a\n"); (err != nil) || (len(entries) != 1) {
+		t.Errorf("pls: got %v, %v", entries, err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"errors"
+	"unicode"
+	"io/fs"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+const profiles_file = "stream_profiles.json"      // in config dir (see config_path)
+
+/* stored stream profile - what the web UI saves and the console client loads */
+type Profile struct {
+	Stream_locations  []interface{}     `json:"stream_locations" mapstructure:"stream_locations"`   // locations or playlist objects
+	Viewports         []Viewport        `json:"viewports,omitempty" mapstructure:"viewports"`
+	Options           map[string]bool   `json:"options,omitempty" mapstructure:"options"`
+}
+
+/* file format of profile_export/profile_import */
+type ProfileExport struct {
+	Format            string            `json:"format" mapstructure:"format"`
+	Name              string            `json:"name" mapstructure:"name"`
+	Profile          *Profile           `json:"profile" mapstructure:"profile"`
+}
+
+const profile_export_format = "fnordstream-profile-v1"
+
+/* playback options known to start_streams */
+var profile_options = map[string]bool{
+	"use_streamlink"     : true,
+	"twitch-disable-ads" : true,
+	"start_muted"        : true,
+	"restart_error"      : true,
+	"restart_user_quit"  : true,
+}
+
+func profile_name_check(name string) error {
+	if (len(name) < 1) || (len(name) > 64) { return errors.New("profile name must have 1-64 characters") }
+	for _, r := range name {
+		if unicode.IsControl(r) { return errors.New("profile name contains control characters") }
+	}
+	return nil
+}
+
+func (profile *Profile) validate() error {
+	if len(profile.Stream_locations) < 1 { return errors.New("profile has no streams") }
+	for idx, entry := range profile.Stream_locations {
+		if _, ok := parse_stream_entry(entry, location_re); !ok {
+			return fmt.Errorf("invalid stream entry %d", idx)
+		}
+	}
+	for idx, vp := range profile.Viewports {
+		if (vp.W < 1) || (vp.H < 1) { return fmt.Errorf("viewport %d has invalid size", idx) }
+	}
+	for name := range profile.Options {
+		if !profile_options[name] { return fmt.Errorf("unknown option %s", name) }
+	}
+	return nil
+}
+
+/* decode & validate profile from a client request - unknown fields are rejected */
+func profile_decode(raw interface{}) (*Profile, error) {
+	profile := &Profile{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused : true,
+		Result      : profile,
+	})
+	if err != nil { return nil, err }
+	if _, ok := raw.(map[string]interface{}); !ok { return nil, errors.New("profile must be an object") }
+	if err = decoder.Decode(raw); err != nil { return nil, err }
+	if err = profile.validate(); err != nil { return nil, err }
+	return profile, nil
+}
+
+/* load profiles - invalid profiles are dropped
+ * an unparseable file is moved aside so it's not overwritten by the next save */
+func profiles_load() map[string]*Profile {
+	log   := logger("profiles")
+	res   := map[string]*Profile{}
+	fname := config_load_path(profiles_file)
+	err   := load_json(fname, &res)
+	if (err != nil) && !errors.Is(err, fs.ErrNotExist) {
+		res = map[string]*Profile{}
+		if fname == config_path(profiles_file) {
+			log.Warn("moving invalid profiles file aside", "file", fname+".invalid")
+			os.Rename(fname, fname+".invalid")
+		}
+	}
+	for name, profile := range res {
+		err := profile_name_check(name)
+		if (err == nil) && (profile == nil) { err = errors.New("empty profile") }
+		if err == nil { err = profile.validate() }
+		if err != nil {
+			log.Warn("dropping invalid profile", "profile", name, "err", err)
+			delete(res, name)
+		}
+	}
+	return res
+}
+
+func profiles_save(profiles map[string]*Profile) error {
+	return save_json(config_path(profiles_file), profiles)
+}
+
+const (
+	profile_history_file = "profile_history.json"   // in config dir
+	profile_history_max  = 20                       // revisions kept per profile
+)
+
+/* one revision of a profile - Profile is nil for deletions */
+type ProfileRevision struct {
+	Rev               int               `json:"rev"`
+	Time              time.Time         `json:"time"`
+	Client            string            `json:"client,omitempty"`   // remote address of the client
+	Action            string            `json:"action"`             // existing, save, import, restore, delete
+	Profile          *Profile           `json:"profile,omitempty"`
+}
+
+func profile_history_load() map[string][]*ProfileRevision {
+	res := map[string][]*ProfileRevision{}
+	if load_json(config_load_path(profile_history_file), &res) != nil {
+		return map[string][]*ProfileRevision{}
+	}
+	return res
+}
+
+/* append revision for a profile change - old is the profile before the change
+ * profiles w/o history (e.g. from older versions) get their old content recorded first */
+func (hub *StreamHub) profile_history_add(client *Client, name string, action string, old *Profile, profile *Profile) {
+	history := hub.profile_history[name]
+	now     := time.Now()
+	if (len(history) < 1) && (old != nil) {
+		history = append(history, &ProfileRevision{ Rev : 1, Time : now, Action : "existing", Profile : old })
+	}
+	rev := 1
+	if len(history) > 0 { rev = history[len(history)-1].Rev + 1 }
+	remote := ""
+	if client != nil { remote = client.remote }
+	history = append(history, &ProfileRevision{
+		Rev     : rev,
+		Time    : now,
+		Client  : remote,
+		Action  : action,
+		Profile : profile,
+	})
+	if len(history) > profile_history_max {
+		history = history[len(history)-profile_history_max:]
+	}
+	hub.profile_history[name] = history
+	save_json(config_path(profile_history_file), hub.profile_history)
+}
+
+func (hub *StreamHub) profile_revision(name string, rev int) *ProfileRevision {
+	for _, revision := range hub.profile_history[name] {
+		if revision.Rev == rev { return revision }
+	}
+	return nil
+}
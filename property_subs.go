@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"regexp"
+)
+
+/* client subscriptions for extra mpv properties
+ * all functions are called from StreamHub.Run() context */
+
+const max_extra_props = 16   // per stream
+
+var prop_name_re = regexp.MustCompile(`^[a-z0-9][a-z0-9/-]{0,63}$`)
+
+func is_base_property(name string) bool {
+	for _, p := range mpv_properties {
+		if p == name { return true }
+	}
+	return false
+}
+
+/* add subscription - first subscriber starts observing the property
+ * not recorded if the stream did not accept the observe control (client may retry) */
+func (hub *StreamHub) property_subscribe(client *Client, stream_id int, name string) bool {
+	if !prop_name_re.MatchString(name) || is_base_property(name) { return false }
+	subs := hub.prop_subs[stream_id]
+	clients, ok := subs[name]
+	if !ok {
+		if len(subs) >= max_extra_props { return false }
+		if !hub.streams[stream_id].Control(&StreamCtl{cmd:"observe", val:name}) { return false }
+		clients    = make(map[*Client]bool)
+		subs[name] = clients
+	}
+	clients[client] = true
+	return true
+}
+
+/* remove subscription - last subscriber stops observing the property
+ * entry is kept w/o subscribers if the stream did not accept the unobserve control
+ * (still observed - retried on the next unsubscribe, reused by the next subscribe) */
+func (hub *StreamHub) property_unsubscribe(client *Client, stream_id int, name string) {
+	subs := hub.prop_subs[stream_id]
+	clients, ok := subs[name]
+	if !ok { return }
+	delete(clients, client)
+	if len(clients) > 0 { return }
+	if !hub.streams[stream_id].Control(&StreamCtl{cmd:"unobserve", val:name}) {
+		hub.log.Warn("unobserve dropped - property still observed", "stream_id", stream_id, "property", name)
+		return
+	}
+	delete(subs, name)
+}
+
+/* drop all subscriptions of a client (on unregister) */
+func (hub *StreamHub) property_unsubscribe_all(client *Client) {
+	for stream_id, subs := range hub.prop_subs {
+		for name := range subs {
+			hub.property_unsubscribe(client, stream_id, name)
+		}
+	}
+}
+
+/* properties subscribed by client */
+func (hub *StreamHub) property_subscriptions(client *Client, stream_id int) []string {
+	res := []string{}
+	for name, clients := range hub.prop_subs[stream_id] {
+		if clients[client] { res = append(res, name) }
+	}
+	sort.Strings(res)
+	return res
+}
+
+/* forward player_property notification to subscribers only */
+func (hub *StreamHub) property_forward(note *Notification) {
+	idx := note.stream_id
+	if (idx < 0) || (idx >= len(hub.prop_subs)) { return }
+	payload, _ := note.payload.(map[string]interface{})
+	name, _    := payload["name"].(string)
+	for client := range hub.prop_subs[idx][name] {
+		hub.try_forward(client, note.json_message)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"time"
+	"regexp"
+	"strconv"
+	"strings"
+	"path/filepath"
+	"encoding/json"
+)
+
+const default_record_template = "stream{id}_{date}_{time}"
+
+type RecordingStatus struct {
+	Recording           bool        `json:"recording"`
+	File                string      `json:"file,omitempty"`
+	Since               time.Time   `json:"since,omitempty"`
+}
+
+var record_name_re = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+/* expand filename template
+ * {id}: stream index, {date}: YYYYMMDD, {time}: HHMMSS, {location}: sanitized location
+ * extension depends on the backend (.mkv for mpv stream-record, .ts for streamlink) */
+func record_filename(config *PlayerConfig, stream_id int, now time.Time) string {
+	template := config.record_template
+	if template == "" { template = default_record_template }
+	location := record_name_re.ReplaceAllString(config.location, "_")
+	if len(location) > 64 { location = location[:64] }
+	name := strings.NewReplacer(
+		"{id}",       strconv.Itoa(stream_id),
+		"{date}",     now.Format("20060102"),
+		"{time}",     now.Format("150405"),
+		"{location}", location,
+	).Replace(template)
+	name = filepath.Base(record_name_re.ReplaceAllString(name, "_"))
+	ext := ".mkv"
+	if config.use_streamlink { ext = ".ts" }
+	return filepath.Join(config.record_dir, name+ext)
+}
+
+/* pick a new recording file - called on record start and on each player (re)start */
+func (stream *Stream) record_next_file() string {
+	now := time.Now()
+	if err := os.MkdirAll(stream.player_cfg.record_dir, 0755); err != nil {
+		stream.log.Warn("cannot create recording directory", "err", err)
+	}
+	stream.record.File  = record_filename(stream.player_cfg, stream.stream_id, now)
+	stream.record.Since = now
+	stream.send_record_note()
+	return stream.record.File
+}
+
+/* start/stop recording
+ * mpv: stream-record property is changed via IPC
+ * streamlink: --record only works at startup - player is restarted */
+func (stream *Stream) record_ctl(val string) {
+	recording := val == "yes"
+	if recording == stream.record.Recording { return }
+	stream.record.Recording = recording
+
+	if stream.player_cfg.use_streamlink {
+		if !recording {
+			stream.record.File = ""
+			stream.send_record_note()
+		}
+		if (stream.state != ST_Stopped) && (stream.target_state == UR_Play) {
+			stream.request_state("restart")     // new file is picked in player_start()
+		} else if recording {
+			stream.send_record_note()           // pending until the player is started
+		}
+		return
+	}
+
+	if !recording {
+		stream.record.File    = ""
+		stream.record_pending = false
+		stream.send_record_note()
+		stream.record_ipc("")
+		return
+	}
+	if stream.state == ST_Stopped {
+		stream.send_record_note()           // new file is picked in player_start()
+		return
+	}
+	file := stream.record_next_file()
+	if stream.ipc_good {
+		stream.record_ipc(file)
+	} else {
+		stream.record_pending = true        // applied once IPC is connected
+	}
+}
+
+/* set mpv stream-record property (empty file stops recording) */
+func (stream *Stream) record_ipc(file string) {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"command" : []interface{}{"set_property", "stream-record", file},
+	})
+	stream.ipc_write(string(msg)+"\n")
+}
+
+/* player args for recording (if active) */
+func (stream *Stream) record_args() (mpv_args []string, streamlink_args []string) {
+	stream.record_pending = false
+	if !stream.record.Recording { return }
+	file := stream.record_next_file()
+	if stream.player_cfg.use_streamlink {
+		streamlink_args = []string{"--force", "--record", file}
+	} else {
+		mpv_args = []string{"--stream-record=" + file}
+	}
+	return
+}
+
+func (stream *Stream) send_record_note() {
+	status      := stream.record
+	json_msg, _ := json.Marshal(&status)
+	stream.notifications <- &Notification{
+		stream_id    : stream.stream_id,
+		notification : "recording_status",
+		payload      : &status,
+		json_message : json_msg,
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"time"
+	"sort"
+	"errors"
+	"strconv"
+
+	"github.com/robfig/cron/v3"
+)
+
+const schedule_file = "stream_schedule.json"     // in config dir (see config_path)
+
+/* scheduled profile start/stop - either cron-like (repeating) or one-off (at) */
+type ScheduleEntry struct {
+	Id                  string      `json:"id" mapstructure:"id"`
+	Action              string      `json:"action" mapstructure:"action"`                 // start, stop
+	Profile             string      `json:"profile,omitempty" mapstructure:"profile"`     // required for start
+	Cron                string      `json:"cron,omitempty" mapstructure:"cron"`           // standard 5 field cron spec
+	At                 *time.Time   `json:"at,omitempty" mapstructure:"-"`                // one-off timestamp
+	Next                time.Time   `json:"next" mapstructure:"-"`
+
+	schedule            cron.Schedule
+}
+
+func (entry *ScheduleEntry) validate(now time.Time) error {
+	switch entry.Action {
+		case "start":
+			if entry.Profile == "" { return errors.New("start action needs a profile") }
+		case "stop":
+		default:
+			return errors.New("invalid action (start/stop)")
+	}
+	if (entry.Cron == "") == (entry.At == nil) {
+		return errors.New("either cron or at must be given")
+	}
+	if entry.Cron != "" {
+		sched, err := cron.ParseStandard(entry.Cron)
+		if err != nil { return err }
+		entry.schedule = sched
+		entry.Next     = sched.Next(now)
+	} else {
+		if !entry.At.After(now) { return errors.New("timestamp is in the past") }
+		entry.Next = *entry.At
+	}
+	return nil
+}
+
+/* StreamHub scheduler part - all functions run in StreamHub.Run() context */
+type Scheduler struct {
+	entries               map[string]*ScheduleEntry
+	next_id               int
+	timer                *time.Timer
+	timer_ch            <-chan time.Time
+}
+
+func (hub *StreamHub) schedule_load() {
+	hub.scheduler.entries = make(map[string]*ScheduleEntry)
+	entries := []*ScheduleEntry{}
+	if load_json(config_load_path(schedule_file), &entries) != nil { return }
+	now := time.Now()
+	for _, entry := range entries {
+		if err := entry.validate(now); err != nil {
+			hub.log.Warn("dropping schedule entry", "id", entry.Id, "err", err)
+			continue
+		}
+		hub.scheduler.entries[entry.Id] = entry
+		if id, err := strconv.Atoi(entry.Id); (err == nil) && (id > hub.scheduler.next_id) {
+			hub.scheduler.next_id = id
+		}
+	}
+	hub.schedule_update()
+}
+
+func (hub *StreamHub) schedule_save() {
+	save_json(config_path(schedule_file), hub.schedule_list())
+}
+
+/* entries ordered by next execution */
+func (hub *StreamHub) schedule_list() []*ScheduleEntry {
+	res := make([]*ScheduleEntry, 0, len(hub.scheduler.entries))
+	for _, entry := range hub.scheduler.entries {
+		res = append(res, entry)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Next.Before(res[j].Next) })
+	return res
+}
+
+func (hub *StreamHub) schedule_add(entry *ScheduleEntry) error {
+	if err := entry.validate(time.Now()); err != nil { return err }
+	hub.scheduler.next_id++
+	entry.Id = strconv.Itoa(hub.scheduler.next_id)
+	hub.scheduler.entries[entry.Id] = entry
+	hub.schedule_save()
+	hub.schedule_update()
+	return nil
+}
+
+func (hub *StreamHub) schedule_delete(id string) bool {
+	if _, ok := hub.scheduler.entries[id]; !ok { return false }
+	delete(hub.scheduler.entries, id)
+	hub.schedule_save()
+	hub.schedule_update()
+	return true
+}
+
+/* (re)arm timer for the next pending entry */
+func (hub *StreamHub) schedule_update() {
+	sched := &hub.scheduler
+	if sched.timer != nil {
+		sched.timer.Stop()
+		sched.timer    = nil
+		sched.timer_ch = nil
+	}
+	next := time.Time{}
+	for _, entry := range sched.entries {
+		if next.IsZero() || entry.Next.Before(next) { next = entry.Next }
+	}
+	if next.IsZero() { return }
+	sched.timer    = time.NewTimer(time.Until(next))
+	sched.timer_ch = sched.timer.C
+}
+
+/* timer fired - run all due entries */
+func (hub *StreamHub) schedule_run() {
+	now     := time.Now()
+	changed := false
+	for _, entry := range hub.schedule_list() {
+		if entry.Next.After(now) { break }
+		err := hub.schedule_exec(entry)
+		res := map[string]interface{}{ "entry" : entry }
+		if err != nil {
+			hub.log.Warn("scheduled action failed", "id", entry.Id, "action", entry.Action, "profile", entry.Profile, "err", err)
+			res["error"] = err.Error()
+			hub.send_response(nil, "schedule_failed", res)
+		} else {
+			hub.log.Info("scheduled action fired", "id", entry.Id, "action", entry.Action, "profile", entry.Profile)
+			hub.send_response(nil, "schedule_fired", res)
+		}
+		if entry.schedule != nil {
+			entry.Next = entry.schedule.Next(now)
+		} else {                                      // one-off entry done
+			delete(hub.scheduler.entries, entry.Id)
+			changed = true
+		}
+	}
+	if changed { hub.schedule_save() }
+	hub.schedule_update()
+}
+
+func (hub *StreamHub) schedule_exec(entry *ScheduleEntry) error {
+	if entry.Action == "stop" {
+		stop_streams(hub, nil, nil)
+		return nil
+	}
+	return hub.profile_start(entry.Profile)
+}
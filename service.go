@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"net"
+	"time"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/* service mode (-daemon): systemd notifications (Type=notify, WatchdogSec=), PID file,
+ * autostart of a stored profile or the last session */
+
+const session_file = "session.json"      // in config dir (see config_path)
+
+/* send state to systemd (sd_notify protocol) - no-op if not started by systemd */
+func sd_notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" { return nil }
+	if strings.HasPrefix(socket, "@") {    // abstract namespace
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{ Name : socket, Net : "unixgram" })
+	if err != nil { return err }
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+/* watchdog interval requested by systemd (0: none) */
+func sd_watchdog_interval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); (pid != "") && (pid != strconv.Itoa(os.Getpid())) { return 0 }
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if (err != nil) || (usec <= 0) { return 0 }
+	return time.Duration(usec) * time.Microsecond
+}
+
+/* signal readiness, then keep the systemd watchdog happy as long as the hub responds */
+func service_ready(shub *StreamHub) {
+	log := logger("service")
+	if err := sd_notify("READY=1"); err != nil {
+		log.Warn("sd_notify failed", "err", err)
+	}
+	interval := sd_watchdog_interval()
+	if interval == 0 { return }
+	log.Info("systemd watchdog enabled", "interval", interval.String())
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !shub.Ping(interval / 4) {
+				log.Warn("hub not responding - skipping watchdog ping")
+				continue
+			}
+			sd_notify("WATCHDOG=1")
+		}
+	}()
+}
+
+func pid_file_write(fname string) error {
+	return os.WriteFile(fname, []byte(strconv.Itoa(os.Getpid()) + "\n"), 0644)
+}
+
+/* remove PID file if it is still ours */
+func pid_file_remove(fname string) {
+	content, err := os.ReadFile(fname)
+	if (err != nil) || (strings.TrimSpace(string(content)) != strconv.Itoa(os.Getpid())) { return }
+	os.Remove(fname)
+}
+
+/* last start_streams request - saved w/ -resume, removed when streams are stopped by a client */
+func (hub *StreamHub) session_save(request map[string]interface{}) {
+	session := map[string]interface{}{}
+	for _, key := range []string{"streams", "options", "watchdog", "thumbnail_interval"} {
+		if val, ok := request[key]; ok { session[key] = val }
+	}
+	session["request"]   = "start_streams"
+	session["viewports"] = hub.viewports          // keep layout even if it was generated
+	if err := save_json(config_path(session_file), session); err != nil {
+		hub.log.Warn("cannot save session", "err", err)
+	}
+}
+
+func (hub *StreamHub) session_clear() {
+	err := os.Remove(config_path(session_file))
+	if (err != nil) && !errors.Is(err, os.ErrNotExist) {
+		hub.log.Warn("cannot remove session", "err", err)
+	}
+}
+
+/* start last session (resume) or autostart profile - resumed session wins */
+func service_autostart(shub *StreamHub, profile string, resume bool) {
+	log := logger("service")
+	var request map[string]interface{}
+	if resume {
+		session := map[string]interface{}{}
+		if _, err := os.Stat(config_path(session_file)); (err == nil) && (load_json(config_path(session_file), &session) == nil) {
+			log.Info("resuming last session")
+			session["request"] = "start_streams"
+			request = session
+		}
+	}
+	if (request == nil) && (profile != "") {
+		log.Info("starting autostart profile", "profile", profile)
+		request = map[string]interface{}{ "request" : "profile_start", "profile_name" : profile }
+	}
+	if request == nil { return }
+
+	client := &Client{
+		client_request : make(chan map[string]interface{}),
+		remote         : "autostart",
+	}
+	shub.Register <- client
+	client.client_request <- request
+	shub.Unregister <- client
+	close(client.client_request)
+}
@@ -9,13 +9,12 @@ import (
 	"strings"
 	"regexp"
 	"runtime"
+	"log/slog"
 	"encoding/json"
 	"github.com/go-cmd/cmd"
 	"github.com/mitchellh/mapstructure"
 )
 
-const stream_debug = false
-
 type StreamState int
 const (
 	ST_Stopped           StreamState = iota
@@ -65,6 +64,7 @@ func (tt TickerTarget) String() string {
 type StreamCtl struct {
 	cmd       string
 	val       string
+	mpv_cmd []interface{}     // mpv IPC command (see stream_ctl.go)
 }
 
 type BufSync struct {
@@ -75,6 +75,7 @@ type BufSync struct {
 type Stream struct {
 	notifications            chan<- *Notification
 	stream_id                int
+	log                     *slog.Logger
 
 	player_cfg              *PlayerConfig
 	state                    StreamState
@@ -89,10 +90,49 @@ type Stream struct {
 	// synchronization stuff for buffer-duration
 	buf_sync                 BufSync
 
+	// watchdog for frozen players
+	watchdog                *time.Ticker
+	watchdog_ch            <-chan time.Time
+	last_activity            time.Time          // last demuxer-cache-duration update
+	paused_since             time.Time          // start of paused-for-cache (zero if not paused)
+	user_paused              bool               // paused by user - no cache updates, no stall check
+	stalled                  bool
+
+	// extra properties observed on client request (rate limited)
+	extra_props              map[string]int     // property -> observe id
+	next_prop_id             int
+	prop_pending             map[string]*Notification
+	prop_ticker             *time.Ticker
+	prop_ticker_ch         <-chan time.Time
+
+	// playlist/rotation
+	playlist_pos             int
+	rotate_ticker           *time.Ticker
+	rotate_ticker_ch       <-chan time.Time
+
+	// failover to backup locations
+	source_idx               int                // 0: primary
+	error_count              int                // consecutive player errors
+	failback_ticker         *time.Ticker
+	failback_ticker_ch     <-chan time.Time
+	probe_cmd               *cmd.Cmd            // primary health probe (stopped by failback_stop())
+	probe_status           <-chan cmd.Status
+
+	// recording
+	record                   RecordingStatus
+	record_pending           bool               // stream-record to be set once IPC is up
+
+	// periodic snapshots (thumbnails)
+	snapshot_ticker         *time.Ticker
+	snapshot_ticker_ch     <-chan time.Time
+
 	// Player stuff
 	player_cmd              *cmd.Cmd
 	//cmd_status              *cmd.Status        // last player cmd.Status
 	cmd_status             <-chan cmd.Status
+	player_stdout          <-chan string
+	player_stderr          <-chan string
+	last_error               string             // last stderr line of player
 
 	// ticker for player/IPC restart
 	ticker_ch              <-chan time.Time
@@ -103,6 +143,8 @@ type Stream struct {
 	ipc_conn                 net.Conn
 	ipc_good                 bool
 	player_events          <-chan *Notification
+
+	done                     chan struct{}      // closed when run() is done (player stopped)
 }
 
 /* user interface */
@@ -110,23 +152,33 @@ func NewStream(notifications chan<- *Notification, stream_id int, player_cfg *Pl
 	stream := &Stream{
 		notifications : notifications,
 		stream_id     : stream_id,
+		log           : stream_logger(stream_id),
 
 		player_cfg    : player_cfg,
 
 		ctl_chan      : make(chan *StreamCtl, 16),
 		//shutdown      : make(chan struct{}),
+
+		extra_props   : make(map[string]int),
+		prop_pending  : make(map[string]*Notification),
+
+		done          : make(chan struct{}),
 	}
+	stream.playlist_start()
 	go stream.run()
 	return stream
 }
 
-func (stream * Stream) Control(ctl *StreamCtl) {
-	if stream.user_shutdown { return }
+/* returns false if the control was dropped */
+func (stream * Stream) Control(ctl *StreamCtl) bool {
+	if stream.user_shutdown { return false }
 	select {
 		/* send non-blocking so a slow/blocked Stream instance
 		 * won't block the StreamHub */
 		case stream.ctl_chan <- ctl:
+			return true
 		default:
+			return false
 	}
 }
 
@@ -138,22 +190,26 @@ func (stream * Stream) Shutdown() {
 	if stream.user_shutdown { return }
 	stream.user_shutdown = true
 	close(stream.ctl_chan)
-	// TODO: wait? (blocking read on shutdown channel)
+}
+
+/* closed once the stream is shut down and its player is gone */
+func (stream * Stream) Done() <-chan struct{} {
+	return stream.done
 }
 
 /* internal stuff follows
  * all internal functions are called from stream.run() goroutine */
 
+/* state machine trace - enabled at runtime via stream_debug */
 func (stream * Stream) debug() {
-	if !stream_debug { return }
+	if !stream_debug.Load() { return }
 	pc, _, _, ok := runtime.Caller(1)
 	details := runtime.FuncForPC(pc)
 	if !ok || (details == nil) { return }
 	fn := details.Name()
 	re := regexp.MustCompile(`^.+\.`)
 	fn = re.ReplaceAllString(fn,"")
-	fmt.Printf("stream[%d].%-16s %-16s %-10s %-16s\n",
-		stream.stream_id, fn, stream.state, stream.target_state, stream.ticker_target)
+	stream.log.Debug(fn, "state", stream.state, "target", stream.target_state, "ticker", stream.ticker_target)
 }
 
 /* started in goroutine from NewStream() */
@@ -173,32 +229,98 @@ func (stream * Stream) run() {
 
 				if ctl.cmd == "play" {
 					stream.request_state(ctl.val)
+				} else if (ctl.cmd == "observe") || (ctl.cmd == "unobserve") {
+					stream.property_observe(ctl)
+				} else if ctl.cmd == "snapshot" {
+					stream.snapshot()
+				} else if ctl.cmd == "record" {
+					stream.record_ctl(ctl.val)
+				} else if ctl.cmd == "playlist" {
+					stream.playlist_ctl(ctl.val)
 				} else { stream.player_ctl(ctl)	}
 
 			// command status channel for player command (fires on player exit)
 			case cmd_status := <- stream.cmd_status:
 				stream.cmd_status = nil
-				if stream_debug {
-					fmt.Println("cmd_status",stream.stream_id,cmd_status.Exit,stream.ticker_target)
+				// output channels are closed by now - drain remaining lines
+				stream.player_output_drain()
+				if stream_debug.Load() {
+					stream.log.Debug("cmd_status", "exit", cmd_status.Exit, "ticker", stream.ticker_target)
 				}
 				stream.player_stopped(&cmd_status)
 
+			// player output (stdout/stderr) - closed on player exit
+			case line, ok := <-stream.player_stdout:
+				if !ok { stream.player_stdout = nil; break }
+				stream.player_output("stdout", line)
+			case line, ok := <-stream.player_stderr:
+				if !ok { stream.player_stderr = nil; break }
+				stream.player_output("stderr", line)
+
 			// timer
 			case _ = <-stream.ticker_ch:
 				stream.ticker_evt()
 
+			// stall detection
+			case _ = <-stream.watchdog_ch:
+				stream.watchdog_check()
+
+			// playlist rotation
+			case _ = <-stream.rotate_ticker_ch:
+				stream.playlist_ctl("next")
+
+			// primary location health check (failback)
+			case _ = <-stream.failback_ticker_ch:
+				stream.failback_probe()
+			case probe_status := <-stream.probe_status:
+				stream.failback_probed(&probe_status)
+
+			// periodic snapshot
+			case _ = <-stream.snapshot_ticker_ch:
+				stream.snapshot()
+
+			// forward rate limited property updates
+			case _ = <-stream.prop_ticker_ch:
+				stream.property_flush()
+
 			/* use an extra channel and forward player events here
 			 * this is done to prevent player events arriving after
 			 * the player has been stopped */
 			case player_evt, ok := <-stream.player_events:
 				if ok {
-					stream.notifications <- player_evt
-					// change to playing status?
 					evt := PlayerEvent{}
 					mapstructure.Decode(player_evt.payload, &evt)
+					// snapshot reply - not forwarded
+					if (evt.Event == "") && (evt.Request_id == snapshot_request_id) {
+						stream.snapshot_done(&evt)
+						break
+					}
+					// extra properties (observe id > 0) are forwarded by property_flush()
+					if (evt.Event == "property-change") && (evt.Id > 0) {
+						stream.property_update(&evt, player_evt)
+						break
+					}
+					stream.notifications <- player_evt
+					// change to playing status?
 					if evt.Event == "playback-restart" {
+						stream.failover_ok()
 						stream.send_status_note("playing", nil)
+					} else if (evt.Event == "property-change") && (evt.Name == "pause") {
+						paused, _ := evt.Data.(bool)
+						if stream.user_paused && !paused {
+							stream.watchdog_activity()    // re-arm stall check
+						}
+						stream.user_paused = paused
+					} else if (evt.Event == "property-change") && (evt.Name == "paused-for-cache") {
+						paused, _ := evt.Data.(bool)
+						if !paused {
+							stream.paused_since = time.Time{}
+							stream.watchdog_activity()
+						} else if stream.paused_since.IsZero() {
+							stream.paused_since = time.Now()
+						}
 					} else if (evt.Event == "property-change") && (evt.Name == "demuxer-cache-duration") {
+						stream.watchdog_activity()
 						dur, ok := evt.Data.(float64)
 						if ok {
 							res := stream.buffer_duration(dur)
@@ -223,6 +345,18 @@ func (stream * Stream) run() {
 
 		} // select
 	} // for loop
+
+	if stream.prop_ticker != nil {
+		stream.prop_ticker.Stop()
+	}
+	if stream.rotate_ticker != nil {
+		stream.rotate_ticker.Stop()
+	}
+	stream.failback_stop()
+	if len(stream.player_cfg.ipc_pipe) > 0 {
+		remove_pipe(stream.player_cfg.ipc_pipe)
+	}
+	close(stream.done)
 }
 
 /* stopping   : stop in progress
@@ -230,7 +364,8 @@ func (stream * Stream) run() {
  * starting   : player startup triggered
  * restarting : restart triggered by user - info for user(s) only
  *              starting note will be sent when new player starts
- * playing    : mpv started playing (triggered by playback-restart event received) */
+ * playing    : mpv started playing (triggered by playback-restart event received)
+ * stalled    : watchdog detected a frozen player (see watchdog_check()) */
 func (stream * Stream) send_status_note(status string, cmd_status *cmd.Status) {
 	if stream.last_status_note == status { return }
 	stream.last_status_note = status
@@ -243,6 +378,7 @@ func (stream * Stream) send_status_note(status string, cmd_status *cmd.Status) {
 		if cmd_status.Error != nil {
 			player_status.Error = cmd_status.Error.Error()
 		}
+		player_status.Last_error = stream.last_error
 	}
 
 	json_msg, _ := json.Marshal(player_status)
@@ -285,6 +421,16 @@ func (stream * Stream) schedule_restart(cmd_status *cmd.Status) time.Duration {
 
 	config := stream.player_cfg
 
+	// switch to backup location after repeated errors?
+	if delay := stream.failover(cmd_status); delay >= 0 {
+		return delay
+	}
+
+	// advance playlist on EOF/error?
+	if delay := stream.playlist_advance(cmd_status); delay >= 0 {
+		return delay
+	}
+
 	if (cmd_status.Exit == 0) && config.restart_user_quit {
 		// player quit by user
 		return time.Duration(0)
@@ -299,6 +445,9 @@ func (stream * Stream) schedule_restart(cmd_status *cmd.Status) time.Duration {
 
 func (stream * Stream) player_stopped(cmd_status *cmd.Status) {
 	stream.debug()
+	if cmd_status != nil {
+		stream.log.Info("player exited", "exit_code", cmd_status.Exit, "last_error", stream.last_error)
+	}
 	stream.ticker_stop()
 	stream.state         = ST_Stopped
 	note                := "stopped"
@@ -377,7 +526,7 @@ func (stream *Stream) ticker_evt() {
 			}
 		default:
 			// shouldn't happend
-			fmt.Println("stream: spurious ticker evt!", "state:", stream.state)
+			stream.log.Warn("spurious ticker evt", "state", stream.state)
 			stream.ticker_stop()
 	}
 }
@@ -426,47 +575,110 @@ func (stream * Stream) player_stop() {
 func (stream * Stream) player_start() {
 	stream.debug()
 	config          := stream.player_cfg
-	player_cmd      := "streamlink"
+	player_cmd      := config.streamlink_cmd
 	var player_args  = []string{}
 	mpv_args        := config.mpv_args[:]
 
 	if len(config.ipc_pipe) > 0 {
+		if err := remove_stale_pipe(config.ipc_pipe); err != nil {
+			stream.log.Warn("IPC pipe", "pipe", config.ipc_pipe, "err", err)
+		}
 		mpv_args = append(mpv_args, "--input-ipc-server=" + config.ipc_pipe)
 	}
 
+	record_mpv_args, record_streamlink_args := stream.record_args()
+	mpv_args = append(mpv_args, record_mpv_args...)
+
+	entry_mpv_args, entry_streamlink_args := config.entry_args()
+
 	if config.use_streamlink {
 		player_args = append(player_args, config.streamlink_args...)
+		player_args = append(player_args, record_streamlink_args...)
+		player_args = append(player_args, entry_streamlink_args...)
 		player_args = append(player_args, "-a", strings.Join(mpv_args," "), config.location, "best")
 	} else {
-		player_cmd  = "mpv"
+		player_cmd  = config.mpv_cmd
 		player_args = append(player_args, mpv_args...)
+		player_args = append(player_args, entry_mpv_args...)
 		player_args = append(player_args, config.location)
 	}
 
 	//fmt.Println(config.mpv_args)
 	//fmt.Println(player_cmd, "\""+strings.Join(player_args,"\" \"")+"\"")
 
-	cmdOptions        := cmd.Options{ Buffered:  false, Streaming: false }
-	stream.player_cmd  = cmd.NewCmdOptions(cmdOptions, player_cmd, player_args...)
-	stream.cmd_status  = stream.player_cmd.Start()
+	stream.log.Info("starting player", "cmd", player_cmd.Path, "location", config.location)
+
+	cmdOptions           := cmd.Options{ Buffered:  false, Streaming: true }
+	stream.player_cmd     = player_cmd.new_cmd(cmdOptions, player_args...)
+	stream.player_stdout  = stream.player_cmd.Stdout
+	stream.player_stderr  = stream.player_cmd.Stderr
+	stream.last_error     = ""
+	stream.cmd_status     = stream.player_cmd.Start()
+}
+
+/* forward a player output line to the StreamHub */
+func (stream * Stream) player_output(src string, line string) {
+	if len(line) < 1 { return }
+	if src == "stderr" {
+		stream.last_error = line
+	}
+	log_line := &PlayerLogLine{
+		Ts   : time.Now(),
+		Src  : src,
+		Line : line,
+	}
+	json_msg, _ := json.Marshal(log_line)
+	note := &Notification{
+		stream_id    : stream.stream_id,
+		notification : "player_log",
+		payload      : log_line,
+		json_message : json_msg,
+	}
+	stream.notifications <- note
+}
+
+/* read remaining output lines after the player exited */
+func (stream * Stream) player_output_drain() {
+	if stream.player_stdout != nil {
+		for line := range stream.player_stdout {
+			stream.player_output("stdout", line)
+		}
+		stream.player_stdout = nil
+	}
+	if stream.player_stderr != nil {
+		for line := range stream.player_stderr {
+			stream.player_output("stderr", line)
+		}
+		stream.player_stderr = nil
+	}
+}
+
+/* write raw command(s) to player IPC connection */
+func (stream * Stream) ipc_write(str string) {
+	if !stream.ipc_good { return }
+	stream.ipc_conn.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+	stream.ipc_conn.Write([]byte(str))
 }
 
 /* send control command to player via IPC connection */
 func (stream * Stream) player_ctl(ctl *StreamCtl) {
-	var str string
 	if !stream.ipc_good { return }
-	switch ctl.cmd {
-	case "quit" : str = `{"command":["quit"]}`+"\n"
-	case "seek" : str = fmt.Sprintf(`{"command":["osd-msg-bar","%s","%s"]}`+"\n",ctl.cmd,ctl.val)
-	default     : str = fmt.Sprintf(`{"command":["osd-msg-bar","set","%s","%s"]}`+"\n",ctl.cmd,ctl.val)
+	mpv_cmd := ctl.mpv_cmd
+	if ctl.cmd == "quit" {
+		mpv_cmd = []interface{}{"quit"}
 	}
-	stream.ipc_conn.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
-	stream.ipc_conn.Write([]byte(str))
+	if mpv_cmd == nil { return }
+	msg, err := json.Marshal(map[string]interface{}{"command" : mpv_cmd})
+	if err != nil { return }
+	stream.ipc_write(string(msg)+"\n")
 }
 
 /* shutdown IPC connection (if not yet done) */
 func (stream *Stream) ipc_shutdown() {
 	if !stream.ipc_good { return }
+	stream.watchdog_stop()
+	stream.snapshot_stop()
+	clear(stream.prop_pending)
 	stream.ipc_good      = false
 	stream.ipc_conn      = nil
 	stream.player_events = nil
@@ -480,23 +692,26 @@ func (stream *Stream) ipc_shutdown() {
 func (stream *Stream) ipc_start() (<-chan *Notification, error) {
 	ipc_conn, err := dial_pipe(stream.player_cfg.ipc_pipe)
 	stream.ipc_conn = ipc_conn
-	if err != nil { return nil, err }
+	if err != nil {
+		stream.log.Debug("IPC connect failed", "err", err)
+		return nil, err
+	}
 
 	stream.buf_sync.start_ts = time.Time{}
 
 	// TODO: move to goroutine?
-	err = player_observe_properties(&ipc_conn)
+	err = player_observe_properties(&ipc_conn, stream.extra_props)
 	if err != nil {
 		ipc_conn.Close()
 		stream.ipc_conn = nil
-		//log.Println(err)
+		stream.log.Warn("observe properties failed", "err", err)
 		return nil, err
 	}
 
 	stream.ipc_good = true
 
 	ipc_conn.SetWriteDeadline(time.Time{})
-	notes := make(chan *Notification, 8)
+	notes := make(chan *Notification, 64)
 
 	// abort if user requested stop/restart
 	// TODO: move before player_observe_properties ?
@@ -508,6 +723,12 @@ func (stream *Stream) ipc_start() (<-chan *Notification, error) {
 	}
 
 	stream.state = ST_IPC_Connected
+	stream.watchdog_start()
+	stream.snapshot_start()
+	if stream.record_pending {
+		stream.record_pending = false
+		stream.record_ipc(stream.record.File)
+	}
 
 	// receiver goroutine
 	go func() {
@@ -548,37 +769,44 @@ func (stream *Stream) ipc_start() (<-chan *Notification, error) {
 	return notes, err
 }
 
-/* register value change notifications for certain player properties via player IPC conn */
-func player_observe_properties(conn *net.Conn) error {
-	mpv_properties := [...]string{
-		"mute", "volume",
-		//"time-pos",
-		"media-title",
-		"video-format", "video-codec", "video-bitrate",
-		"width", "height",
+/* properties observed for all players - forwarded as player_event */
+var mpv_properties = [...]string{
+	"mute", "volume",
+	"pause",               // watchdog: paused streams don't update demuxer-cache-duration
+	//"time-pos",          // high update rate - available through subscribe_properties
+	"media-title",
+	"video-format", "video-codec", "video-bitrate",
+	"width", "height",
 
-		/* Approximate time of video buffered in the demuxer, in seconds.
-		Same as demuxer-cache-duration but returns the last timestamp of buffered data in demuxer.
-		* unsuitable: 10 13932.024222 map[name:demuxer-cache-time]} for ~10s cache delay */
-		//"demuxer-cache-time",
+	/* Approximate time of video buffered in the demuxer, in seconds.
+	Same as demuxer-cache-duration but returns the last timestamp of buffered data in demuxer.
+	* unsuitable: 10 13932.024222 map[name:demuxer-cache-time]} for ~10s cache delay */
+	//"demuxer-cache-time",
 
-		/* 3073 property updates during reference run
-		 * much more precise than demuxer-cache-duration */
-		//"time-remaining",
+	/* 3073 property updates during reference run
+	 * much more precise than demuxer-cache-duration */
+	//"time-remaining",
 
-		/* 240 property updates during reference run
-		 * less precise than time-remaining */
-		"demuxer-cache-duration",
+	/* 240 property updates during reference run
+	 * less precise than time-remaining */
+	"demuxer-cache-duration",
 
-		"paused-for-cache",
+	"paused-for-cache",
 
-		/* this is false for streaming */
-		// "partially-seekable",
-	}
+	/* this is false for streaming */
+	// "partially-seekable",
+}
+
+/* register value change notifications for certain player properties via player IPC conn
+ * extra properties are observed with their own id so they can be told apart */
+func player_observe_properties(conn *net.Conn, extra_props map[string]int) error {
 	var msg = make([]byte, 0, 1024)
 	for _, p := range mpv_properties {
 		msg = append(msg, "{\"command\":[\"observe_property\",0,\""+p+"\"]}\n"...)
 	}
+	for p, id := range extra_props {
+		msg = append(msg, fmt.Sprintf(`{"command":["observe_property",%d,"%s"]}`+"\n", id, p)...)
+	}
 	(*conn).SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
 	_, err := (*conn).Write(msg)
 	return err
@@ -602,3 +830,106 @@ func (stream *Stream) buffer_duration(current_duration float64) (res float64) {
 	bs.min_duration = current_duration
 	return
 }
+
+/* start watchdog once the IPC connection is up */
+func (stream *Stream) watchdog_start() {
+	cfg := &stream.player_cfg.watchdog
+	if (cfg.Stall_timeout <= 0) && (cfg.Cache_timeout <= 0) { return }
+	stream.last_activity = time.Now()
+	stream.paused_since  = time.Time{}
+	stream.user_paused   = false
+	stream.stalled       = false
+	stream.watchdog      = time.NewTicker(time.Second)
+	stream.watchdog_ch   = stream.watchdog.C
+}
+
+func (stream *Stream) watchdog_stop() {
+	if stream.watchdog_ch == nil { return }
+	stream.watchdog.Stop()
+	stream.watchdog    = nil
+	stream.watchdog_ch = nil
+	stream.stalled     = false
+}
+
+/* demuxer-cache-duration update received - player is alive */
+func (stream *Stream) watchdog_activity() {
+	stream.last_activity = time.Now()
+	if stream.stalled && stream.paused_since.IsZero() {
+		stream.stalled = false
+		stream.log.Info("player recovered from stall")
+		stream.send_status_note("playing", nil)
+	}
+}
+
+/* check for frozen player: no demuxer-cache-duration updates
+ * or stuck in paused-for-cache (not while paused by the user) */
+func (stream *Stream) watchdog_check() {
+	if stream.stalled || stream.user_paused || (stream.target_state != UR_Play) { return }
+
+	cfg    := &stream.player_cfg.watchdog
+	now    := time.Now()
+	reason := ""
+
+	if (cfg.Stall_timeout > 0) && (now.Sub(stream.last_activity).Seconds() > cfg.Stall_timeout) {
+		reason = "no demuxer-cache-duration updates"
+	} else if (cfg.Cache_timeout > 0) && (!stream.paused_since.IsZero()) &&
+		(now.Sub(stream.paused_since).Seconds() > cfg.Cache_timeout) {
+		reason = "stuck in paused-for-cache"
+	}
+	if reason == "" { return }
+
+	stream.stalled = true
+	stream.log.Warn("player stalled", "reason", reason, "restart", cfg.Restart)
+	stream.send_status_note("stalled", nil)
+
+	if cfg.Restart {
+		stream.request_state("restart")
+	}
+}
+
+/* interval for forwarding updates of extra properties */
+const prop_rate_interval = 500 * time.Millisecond
+
+/* start/stop observing an extra property (refcounting is done by the StreamHub) */
+func (stream *Stream) property_observe(ctl *StreamCtl) {
+	name := ctl.val
+	id, observed := stream.extra_props[name]
+
+	if ctl.cmd == "observe" {
+		if observed { return }
+		stream.next_prop_id++
+		id = stream.next_prop_id
+		stream.extra_props[name] = id
+		stream.ipc_write(fmt.Sprintf(`{"command":["observe_property",%d,"%s"]}`+"\n", id, name))
+		if stream.prop_ticker_ch == nil {
+			stream.prop_ticker    = time.NewTicker(prop_rate_interval)
+			stream.prop_ticker_ch = stream.prop_ticker.C
+		}
+		return
+	}
+
+	// unobserve
+	if !observed { return }
+	delete(stream.extra_props, name)
+	delete(stream.prop_pending, name)
+	stream.ipc_write(fmt.Sprintf(`{"command":["unobserve_property",%d]}`+"\n", id))
+	if (len(stream.extra_props) == 0) && (stream.prop_ticker_ch != nil) {
+		stream.prop_ticker.Stop()
+		stream.prop_ticker    = nil
+		stream.prop_ticker_ch = nil
+	}
+}
+
+/* keep latest value of an extra property until next property_flush() */
+func (stream *Stream) property_update(evt *PlayerEvent, note *Notification) {
+	if stream.extra_props[evt.Name] != evt.Id { return }   // stale observe id
+	note.notification = "player_property"
+	stream.prop_pending[evt.Name] = note
+}
+
+func (stream *Stream) property_flush() {
+	for name, note := range stream.prop_pending {
+		stream.notifications <- note
+		delete(stream.prop_pending, name)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/* typed stream controls for the stream_ctl request
+ * each control validates its value and builds the StreamCtl for the Stream */
+type CtlHandler func(value interface{}, request map[string]interface{}) (*StreamCtl, error)
+
+var stream_ctls = map[string]CtlHandler{
+	"play"       : ctl_play,
+	"volume"     : ctl_number("volume", 0, 130),
+	"speed"      : ctl_number("speed", 0.01, 100),
+	"mute"       : ctl_flag("mute"),
+	"pause"      : ctl_flag("pause"),
+	"seek"       : ctl_seek,
+	"aid"        : ctl_track("aid"),
+	"sid"        : ctl_track("sid"),
+	"loop-file"  : ctl_loop,
+	"osd"        : ctl_osd,
+	"screenshot" : ctl_screenshot,
+	"snapshot"   : ctl_snapshot,
+	"next"       : ctl_playlist("next"),
+	"previous"   : ctl_playlist("previous"),
+}
+
+/* mpv command for setting a property w/ OSD feedback */
+func mpv_set(property string, val string) *StreamCtl {
+	return &StreamCtl{cmd:property, val:val, mpv_cmd:[]interface{}{"osd-msg-bar", "set", property, val}}
+}
+
+func parse_number(value interface{}) (float64, error) {
+	var res float64
+	var err error
+	switch v := value.(type) {
+		case float64 : res = v
+		case string  : res, err = strconv.ParseFloat(strings.TrimSpace(v), 64)
+		default      : err = errors.New("not a number")
+	}
+	if (err == nil) && (math.IsNaN(res) || math.IsInf(res, 0)) {
+		err = errors.New("not a finite number")
+	}
+	return res, err
+}
+
+func format_number(val float64) string {
+	return strconv.FormatFloat(val, 'f', -1, 64)
+}
+
+/* accepts bool or yes/no strings */
+func parse_flag(value interface{}) (string, error) {
+	switch v := value.(type) {
+		case bool:
+			if v { return "yes", nil }
+			return "no", nil
+		case string:
+			if (v == "yes") || (v == "no") { return v, nil }
+	}
+	return "", fmt.Errorf("invalid flag value %v (yes/no)", value)
+}
+
+func ctl_play(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+	val, _ := value.(string)
+	switch val {
+		case "yes", "no", "restart":
+			return &StreamCtl{cmd:"play", val:val}, nil
+	}
+	return nil, fmt.Errorf("invalid play value %v (yes/no/restart)", value)
+}
+
+func ctl_number(property string, min float64, max float64) CtlHandler {
+	return func(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+		val, err := parse_number(value)
+		if err != nil { return nil, err }
+		if (val < min) || (val > max) {
+			return nil, fmt.Errorf("%s out of range [%g, %g]", property, min, max)
+		}
+		return mpv_set(property, format_number(val)), nil
+	}
+}
+
+func ctl_flag(property string) CtlHandler {
+	return func(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+		val, err := parse_flag(value)
+		if err != nil { return nil, err }
+		return mpv_set(property, val), nil
+	}
+}
+
+/* seek modes: relative (default), absolute, relative-percent, absolute-percent */
+func ctl_seek(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+	val, err := parse_number(value)
+	if err != nil { return nil, err }
+	mode, _ := request["mode"].(string)
+	switch mode {
+		case "":
+			mode = "relative"
+		case "relative", "absolute", "relative-percent", "absolute-percent":
+		default:
+			return nil, fmt.Errorf("invalid seek mode %s", mode)
+	}
+	if strings.HasSuffix(mode, "percent") && ((val < -100) || (val > 100)) {
+		return nil, errors.New("seek percentage out of range")
+	}
+	str := format_number(val)
+	return &StreamCtl{cmd:"seek", val:str, mpv_cmd:[]interface{}{"osd-msg-bar", "seek", str, mode}}, nil
+}
+
+/* track id (>=1), auto or no */
+func ctl_track(property string) CtlHandler {
+	return func(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+		if s, ok := value.(string); ok && ((s == "auto") || (s == "no")) {
+			return mpv_set(property, s), nil
+		}
+		val, err := parse_number(value)
+		if (err != nil) || (val < 1) || (val != math.Trunc(val)) {
+			return nil, fmt.Errorf("invalid track id %v", value)
+		}
+		return mpv_set(property, format_number(val)), nil
+	}
+}
+
+/* loop count, inf or no */
+func ctl_loop(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+	if s, ok := value.(string); ok && ((s == "inf") || (s == "no") || (s == "yes")) {
+		return mpv_set("loop-file", s), nil
+	}
+	val, err := parse_number(value)
+	if (err != nil) || (val < 0) || (val != math.Trunc(val)) {
+		return nil, fmt.Errorf("invalid loop count %v", value)
+	}
+	return mpv_set("loop-file", format_number(val)), nil
+}
+
+/* show text on OSD - optional duration in ms */
+func ctl_osd(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+	msg, ok := value.(string)
+	if !ok { return nil, errors.New("osd message must be a string") }
+	msg = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && (r != '\n') { return -1 }
+		return r
+	}, msg)
+	if len(msg) > 256 { return nil, errors.New("osd message too long") }
+	// mpv expands ${property} in show-text - escape it
+	msg = strings.ReplaceAll(msg, "$", "$$")
+
+	duration := 3000.0
+	if d, ok := request["duration"]; ok {
+		var err error
+		duration, err = parse_number(d)
+		if (err != nil) || (duration < 0) || (duration > 60000) {
+			return nil, errors.New("invalid osd duration")
+		}
+	}
+	return &StreamCtl{cmd:"osd", val:msg, mpv_cmd:[]interface{}{"show-text", msg, format_number(math.Round(duration))}}, nil
+}
+
+/* screenshot (saved by mpv) - video, subtitles (default) or window */
+func ctl_screenshot(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+	flags, _ := value.(string)
+	switch flags {
+		case "":
+			flags = "subtitles"
+		case "video", "subtitles", "window":
+		default:
+			return nil, fmt.Errorf("invalid screenshot mode %v", value)
+	}
+	return &StreamCtl{cmd:"screenshot", val:flags, mpv_cmd:[]interface{}{"screenshot", flags}}, nil
+}
+
+/* update thumbnail now (see thumbnail.go) - value is ignored */
+func ctl_snapshot(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+	return &StreamCtl{cmd:"snapshot"}, nil
+}
+
+/* playlist navigation (see playlist.go) - value is ignored */
+func ctl_playlist(direction string) CtlHandler {
+	return func(value interface{}, request map[string]interface{}) (*StreamCtl, error) {
+		return &StreamCtl{cmd:"playlist", val:direction}, nil
+	}
+}
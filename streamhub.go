@@ -2,17 +2,28 @@ package main
 
 import (
 	//"fmt"
+	"os"
 	"time"
-	"runtime"
 	"strconv"
+	"log/slog"
 )
 
 type Client struct {
 	shub            *StreamHub
 	client_notify    chan []byte
 	client_request   chan map[string]interface{}
+	dropped          int                 // notifications dropped for this client
+	resyncs          int                 // global_status snapshots sent after drops
+	resync           bool                // notifications dropped - snapshot pending (see clients_resync())
+	lagging_since    time.Time
+	remote           string              // remote address (for profile history)
 }
 
+/* slow clients: notifications are dropped until the client caught up,
+ * then a fresh global_status snapshot is sent - clients lagging too long are disconnected */
+const client_resync_interval = 1 * time.Second
+const client_slow_timeout    = 30 * time.Second
+
 type ClientRequest struct {
 	src               *Client
 	request            map[string]interface{}
@@ -31,8 +42,15 @@ type Notification struct {
 type StreamStatus struct {
 	Player_status      string                    `json:"player_status"`
 	Location           string                    `json:"location,omitempty"`
+	Title              string                    `json:"title,omitempty"`          // display label
+	Playlist         []string                    `json:"playlist,omitempty"`       // all locations (if more than one)
+	Playlist_pos       int                       `json:"playlist_pos"`
+	Backups          []string                    `json:"backups,omitempty"`
+	Source             int                       `json:"source"`                   // active source - 0: primary, n: backup n
 	Viewport_id        int                       `json:"viewport_id"`
 	Properties         map[string]interface{}    `json:"properties,omitempty"`
+	Thumbnail         *ThumbnailInfo             `json:"thumbnail,omitempty"`
+	Recording         *RecordingStatus           `json:"recording,omitempty"`
 }
 
 type StreamHub struct {
@@ -45,6 +63,7 @@ type StreamHub struct {
 
 	client_requests       chan *ClientRequest     // channel holding multiplexed requests of all clients (fan-in)
 	notifications         chan *Notification      // channel holding multiplexed notifications for all clients (fan-out)
+	notify_queue        []*Notification           // notifications from Run() context (see hub.send_response())
 
 	/* streams stuff */
 	displays            []Display
@@ -55,14 +74,43 @@ type StreamHub struct {
 	streams_playing       bool
 	streams             []*Stream
 	stream_status       []*StreamStatus
+	stream_logs         []*PlayerLog
+	stream_metrics      []*StreamMetrics
+	prop_subs           []map[string]map[*Client]bool   // extra property subscriptions per stream
 
-	pipe_prefix           string
+	pipe_prefix           string                  // per-instance IPC pipe names (see pipe_setup())
+	pipe_dir              string
+	pipe_gen              int                     // start_streams counter - new pipe names for each stream set
 	restart_error_delay   time.Duration
+	watchdog              WatchdogConfig          // default watchdog settings
+	thumbnail_dir         string                  // latest snapshot of each stream
+	thumbnail_interval    time.Duration           // default snapshot interval (0: on request only)
+	record_dir            string
+	record_template       string
+
+	stream_profiles       map[string]*Profile
+	profile_history       map[string][]*ProfileRevision
+	scheduler             Scheduler
+
+	metrics_req           chan chan<- []byte     // metrics queries from HTTP handler
+	shutdown_req          chan chan []*Stream    // see Shutdown()
+	shutting_down         bool
+	ping_req              chan chan struct{}     // see Ping()
+	resume_session        bool                   // save session for -resume (see session_save())
+	pid_file              string                 // removed on shutdown
+	dropped_notifications int
+	slow_disconnects      int                    // clients disconnected for lagging too long
 
-	stream_profiles       map[string]interface{}
+	config               *ServerConfig           // active settings (see apply_config())
+	config_initial       *ServerConfig           // settings at startup
+	config_file           string                 // reload_config source
+	config_required       bool                   // config file given explicitly (-config)
+	config_overrides      map[string]interface{} // command-line flags
+
+	log                  *slog.Logger
 }
 
-func NewStreamHub() *StreamHub {
+func NewStreamHub(config *ServerConfig) *StreamHub {
 	shub := &StreamHub{
 		Register            : make(chan *Client),
 		Unregister          : make(chan *Client),
@@ -70,19 +118,76 @@ func NewStreamHub() *StreamHub {
 		clients             : make(map[*Client]bool),
 		client_requests     : make(chan *ClientRequest, 64),
 		notifications       : make(chan *Notification, 64),
+		metrics_req         : make(chan chan<- []byte),
+		shutdown_req        : make(chan chan []*Stream),
+		ping_req            : make(chan chan struct{}),
 
-		displays            : displays_detect(),
-		pipe_prefix         : "/tmp/nstream_mpv_ipc",
-		restart_error_delay : 1*time.Second,
+		displays            : displays_detect(config.command("xrandr")),
+		config_initial      : config,
+		log                 : logger("hub"),
+	}
+	shub.apply_config(config)
+	pipe_prefix, pipe_dir, err := pipe_setup(config.Ipc_dir)
+	if err != nil {
+		log_fatal(shub.log, "cannot create IPC directory", "err", err)
 	}
-	if runtime.GOOS == "windows" {
-		shub.pipe_prefix = "\\\\.\\pipe\\nstream_mpv_ipc"
+	shub.pipe_prefix, shub.pipe_dir = pipe_prefix, pipe_dir
+	thumb_dir, err := os.MkdirTemp("", "fnordstream-thumbs-")
+	if err != nil {
+		shub.log.Warn("cannot create thumbnail directory - snapshots disabled", "err", err)
+	} else {
+		shub.thumbnail_dir = thumb_dir
 	}
-	shub.stream_profiles = map[string]interface{} {}
-	load_json("stream_profiles.json", &shub.stream_profiles)
+	shub.stream_profiles = profiles_load()
+	shub.profile_history = profile_history_load()
+	shub.schedule_load()
 	return shub
 }
 
+/* stop all streams & wait (up to timeout) until their players are gone
+ * called from outside of Run() - Run() keeps going to handle notifications of stopping streams */
+func (hub *StreamHub) Shutdown(timeout time.Duration) {
+	if hub.pid_file != "" {
+		defer pid_file_remove(hub.pid_file)
+	}
+	reply := make(chan []*Stream)
+	hub.shutdown_req <- reply
+	streams  := <-reply
+	deadline := time.After(timeout)
+	for _, stream := range streams {
+		select {
+			case <-stream.Done():
+			case <-deadline:
+				hub.log.Warn("shutdown timeout - players still running")
+				return
+		}
+	}
+	if hub.thumbnail_dir != "" {
+		os.RemoveAll(hub.thumbnail_dir)
+	}
+	if hub.pipe_dir != "" {
+		os.RemoveAll(hub.pipe_dir)
+	}
+	hub.log.Info("all streams stopped")
+}
+
+/* hub loop responsive? */
+func (hub *StreamHub) Ping(timeout time.Duration) bool {
+	reply   := make(chan struct{})
+	expired := time.After(timeout)
+	select {
+		case hub.ping_req <- reply:
+		case <-expired:
+			return false
+	}
+	select {
+		case <-reply:
+			return true
+		case <-expired:
+			return false
+	}
+}
+
 func mux_client(hub *StreamHub, client *Client) {
 	for {
 		msg, ok := <- client.client_request
@@ -93,11 +198,53 @@ func mux_client(hub *StreamHub, client *Client) {
 	//fmt.Println("mux_client done")
 }
 
-func try_forward(client *Client, message []byte) {
-	select {
-		case client.client_notify <- message:
-		default:
-			/* client channel full - drop message */
+func (hub * StreamHub) try_forward(client *Client, message []byte) {
+	if client.client_notify == nil { return }
+	if !client.resync {
+		select {
+			case client.client_notify <- message:
+				return
+			default:
+				/* client channel full - drop messages until resync */
+				client.resync        = true
+				client.lagging_since = time.Now()
+				hub.log.Warn("client too slow - dropping notifications until resync", "remote", client.remote)
+		}
+	}
+	client.dropped++
+	hub.dropped_notifications++
+}
+
+/* remove client - closes its notify channel (websocket sender closes the connection then) */
+func (hub *StreamHub) client_remove(client *Client) {
+	if _, ok := hub.clients[client]; !ok { return }
+	hub.property_unsubscribe_all(client)
+	delete(hub.clients, client)
+	if client.client_notify != nil {
+		close(client.client_notify)
+	}
+}
+
+/* send snapshot to clients which dropped notifications & caught up, disconnect clients lagging too long */
+func (hub *StreamHub) clients_resync() {
+	for client := range hub.clients {
+		if !client.resync { continue }
+		if time.Since(client.lagging_since) > client_slow_timeout {
+			hub.log.Warn("disconnecting slow client", "remote", client.remote, "dropped", client.dropped)
+			hub.slow_disconnects++
+			hub.client_remove(client)
+			continue
+		}
+		if len(client.client_notify) > cap(client.client_notify)/2 { continue }    // still busy
+
+		resync, _   := note_json("resync", map[string]interface{}{ "dropped" : client.dropped })
+		status, err := note_json("global_status", hub.global_status_payload())
+		if err != nil { continue }
+		client.client_notify <- resync          // room for both (see above)
+		client.client_notify <- status
+		client.resync = false
+		client.resyncs++
+		hub.log.Info("client resynced", "remote", client.remote, "dropped", client.dropped)
 	}
 }
 
@@ -107,9 +254,20 @@ func try_forward(client *Client, message []byte) {
  * closing this channel only happen in StreamHub.Run().
  * 
  * Therefor client_request() may start go routines to handle certain requests
- * and send the response via the multiplexed client_notifies channel of the StreamHub. */
+ * and send the response via the multiplexed client_notifies channel of the StreamHub.
+ * Responses from Run() context itself go through notify_queue (see hub.send_response()). */
 func (hub * StreamHub) Run() {
+	resync := time.NewTicker(client_resync_interval)
+	defer resync.Stop()
 	for {
+		/* responses of the last event first - keeps their order, Run() never blocks on its own channel */
+		for len(hub.notify_queue) > 0 {
+			note := hub.notify_queue[0]
+			hub.notify_queue[0] = nil
+			hub.notify_queue    = hub.notify_queue[1:]
+			hub.dispatch(note)
+		}
+
 		select {
 
 			/* client register */
@@ -120,12 +278,33 @@ func (hub * StreamHub) Run() {
 
 			/* client unregister */
 			case client := <-hub.Unregister:
-				if _, ok := hub.clients[client]; ok {
-					delete(hub.clients, client)
-					if client.client_notify != nil {
-						close(client.client_notify)
-					}
+				hub.client_remove(client)      // no-op if already disconnected for being slow
+
+			/* slow clients */
+			case <-resync.C:
+				hub.clients_resync()
+
+			/* scheduled profile start/stop */
+			case <-hub.scheduler.timer_ch:
+				hub.schedule_run()
+
+			/* shutdown - stop streams, hand them to Shutdown() for waiting */
+			case reply := <-hub.shutdown_req:
+				streams := []*Stream{}
+				for _, stream := range hub.streams {
+					if stream != nil { streams = append(streams, stream) }
 				}
+				hub.shutting_down = true
+				stop_streams(hub, nil, nil)
+				reply <- streams
+
+			/* liveness check (systemd watchdog) */
+			case reply := <-hub.ping_req:
+				close(reply)
+
+			/* metrics query from HTTP handler */
+			case reply := <-hub.metrics_req:
+				reply <- hub.metrics_text()
 
 			/* client requests - includes client -> player messages */
 			case req := <-hub.client_requests:
@@ -133,27 +312,34 @@ func (hub * StreamHub) Run() {
 
 			/* messages to clients - includes player -> client messages */
 			case note := <-hub.notifications:
-				client       := note.dst
-				json_message := note.json_message
-
-				// prepend JSON data with note type and stream_id
-				if note.stream_id >= 0 {
-					prepend          := `{"notification":"`+note.notification+`","stream_id":`+strconv.Itoa(note.stream_id)+`,"payload":`
-					str              := prepend + string(json_message) + "}"
-					json_message      = []byte(str)
-					note.json_message = json_message
-				}
-
-				/* watch notification and follow certain state/value changes */
-				notification(hub, note)
-
-				if client == nil {                             /* broadcast to all clients */
-					for client := range hub.clients {
-						try_forward(client, json_message)
-					}
-				} else if _, ok := hub.clients[client]; ok {    /* single client only */
-					try_forward(client, json_message)
-				}
+				hub.dispatch(note)
 		} /* select */
 	} /* for */
 }
+
+/* forward notification to its client(s) - executed in StreamHub.Run() context */
+func (hub *StreamHub) dispatch(note *Notification) {
+	client       := note.dst
+	json_message := note.json_message
+
+	// prepend JSON data with note type and stream_id
+	if note.stream_id >= 0 {
+		prepend          := `{"notification":"`+note.notification+`","stream_id":`+strconv.Itoa(note.stream_id)+`,"payload":`
+		str              := prepend + string(json_message) + "}"
+		json_message      = []byte(str)
+		note.json_message = json_message
+	}
+
+	/* watch notification and follow certain state/value changes */
+	notification(hub, note)
+
+	if note.notification == "player_property" {    /* property subscribers only */
+		hub.property_forward(note)
+	} else if client == nil {                      /* broadcast to all clients */
+		for client := range hub.clients {
+			hub.try_forward(client, json_message)
+		}
+	} else if _, ok := hub.clients[client]; ok {    /* single client only */
+		hub.try_forward(client, json_message)
+	}
+}
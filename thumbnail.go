@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"time"
+	"strconv"
+	"strings"
+	"net/http"
+	"path/filepath"
+	"encoding/json"
+)
+
+/* request_id for screenshot-to-file commands - reply triggers thumbnail update */
+const snapshot_request_id = 1
+
+type ThumbnailInfo struct {
+	Url                 string      `json:"url"`
+	Ts                  time.Time   `json:"ts"`
+}
+
+func thumbnail_file(dir string, stream_id int) string {
+	return filepath.Join(dir, "stream"+strconv.Itoa(stream_id)+".jpg")
+}
+
+func thumbnail_url(stream_id int) string {
+	return "/streams/" + strconv.Itoa(stream_id) + "/thumbnail"
+}
+
+/* periodic snapshots while IPC connection is up */
+func (stream *Stream) snapshot_start() {
+	interval := stream.player_cfg.thumbnail_interval
+	if (interval <= 0) || (stream.player_cfg.thumbnail_file == "") { return }
+	stream.snapshot_ticker    = time.NewTicker(interval)
+	stream.snapshot_ticker_ch = stream.snapshot_ticker.C
+}
+
+func (stream *Stream) snapshot_stop() {
+	if stream.snapshot_ticker_ch == nil { return }
+	stream.snapshot_ticker.Stop()
+	stream.snapshot_ticker    = nil
+	stream.snapshot_ticker_ch = nil
+}
+
+/* let mpv write a screenshot to a temp file
+ * file is renamed once the reply arrives (see snapshot_done()) */
+func (stream *Stream) snapshot() {
+	fname := stream.player_cfg.thumbnail_file
+	if (fname == "") || !stream.ipc_good { return }
+	cmd := map[string]interface{}{
+		"command"    : []interface{}{"screenshot-to-file", fname+".tmp.jpg", "video"},
+		"request_id" : snapshot_request_id,
+	}
+	msg, _ := json.Marshal(cmd)
+	stream.ipc_write(string(msg)+"\n")
+}
+
+func (stream *Stream) snapshot_done(evt *PlayerEvent) {
+	fname := stream.player_cfg.thumbnail_file
+	tmp   := fname+".tmp.jpg"
+	if stream.ctl_chan == nil {      // stream stopped - hub removed snapshots (see thumbnails_remove())
+		os.Remove(tmp)
+		return
+	}
+	if evt.Error != "success" {
+		stream.log.Debug("snapshot failed", "err", evt.Error)
+		os.Remove(tmp)
+		return
+	}
+	if err := os.Rename(tmp, fname); err != nil {
+		stream.log.Warn("snapshot rename failed", "err", err)
+		return
+	}
+	info := &ThumbnailInfo{
+		Url : thumbnail_url(stream.stream_id),
+		Ts  : time.Now(),
+	}
+	json_msg, _ := json.Marshal(info)
+	stream.notifications <- &Notification{
+		stream_id    : stream.stream_id,
+		notification : "thumbnail_updated",
+		payload      : info,
+		json_message : json_msg,
+	}
+}
+
+/* remove snapshots of all streams - called by stop_streams */
+func (hub *StreamHub) thumbnails_remove() {
+	if hub.thumbnail_dir == "" { return }
+	for idx := range hub.streams {
+		os.Remove(thumbnail_file(hub.thumbnail_dir, idx))
+	}
+}
+
+/* serves /streams/{id}/thumbnail from the hub thumbnail directory */
+func thumbnail_handler(thumb_dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+		if (len(parts) != 3) || (parts[0] != "streams") || (parts[2] != "thumbnail") {
+			http.NotFound(w, req)
+			return
+		}
+		stream_id, err := strconv.Atoi(parts[1])
+		if (err != nil) || (stream_id < 0) {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeFile(w, req, thumbnail_file(thumb_dir, stream_id))
+	})
+}
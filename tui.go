@@ -0,0 +1,317 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"sync"
+	"strings"
+	"encoding/json"
+
+	"golang.org/x/term"
+)
+
+/* full-screen terminal dashboard (-tui) - registers as Client on the StreamHub
+ * keys: up/down (j/k) select, m mute, +/- volume, p play, s stop, r restart,
+ *       M mute all, X stop all streams, q quit */
+
+const tui_max_events = 200
+
+/* log output while the TUI owns the terminal - lines end up in the event log */
+type TuiLog chan string
+
+func (tl TuiLog) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+	if strings.HasPrefix(line, "time=") {            // events have their own timestamp
+		if _, rest, ok := strings.Cut(line, " "); ok { line = rest }
+	}
+	select {
+		case tl <- line:
+		default:                                      // TUI busy - drop line
+	}
+	return len(p), nil
+}
+
+type Tui struct {
+	client             *Client
+	playing             bool
+	streams           []*StreamStatus
+	events            []string
+	selected            int
+	quit                bool
+	dirty               bool              // redraw needed
+	width, height       int               // terminal size of last redraw
+}
+
+func (tui *Tui) event(format string, args ...interface{}) {
+	line := time.Now().Format("15:04:05 ") + fmt.Sprintf(format, args...)
+	tui.events = append(tui.events, line)
+	if len(tui.events) > tui_max_events {
+		tui.events = tui.events[len(tui.events)-tui_max_events:]
+	}
+}
+
+func (tui *Tui) request(request map[string]interface{}) {
+	tui.client.client_request <- request
+}
+
+func (tui *Tui) stream_ctl(stream_id interface{}, ctl string, value interface{}) {
+	tui.request(map[string]interface{}{
+		"request"   : "stream_ctl",
+		"stream_id" : stream_id,
+		"ctl"       : ctl,
+		"value"     : value,
+	})
+}
+
+func (tui *Tui) stream(idx int) *StreamStatus {
+	if (idx < 0) || (idx >= len(tui.streams)) { return nil }
+	return tui.streams[idx]
+}
+
+/* keyboard input - unknown keys are ignored */
+func (tui *Tui) key(key string) {
+	sel    := float64(tui.selected)
+	stream := tui.stream(tui.selected)
+	switch key {
+		case "q", "\x03":
+			tui.quit = true
+		case "k", "\x1b[A":
+			if tui.selected > 0 { tui.selected-- }
+		case "j", "\x1b[B":
+			if tui.selected < len(tui.streams)-1 { tui.selected++ }
+		case "M":
+			tui.stream_ctl("*", "mute", "yes")
+		case "X":
+			tui.request(map[string]interface{}{ "request" : "stop_streams" })
+	}
+	if stream == nil { return }
+	switch key {
+		case "m":
+			muted, _ := stream.Properties["mute"].(bool)
+			tui.stream_ctl(sel, "mute", !muted)
+		case "+", "-":
+			volume, ok := stream.Properties["volume"].(float64)
+			if !ok { return }
+			if key == "+" { volume += 5 } else { volume -= 5 }
+			if volume < 0 { volume = 0 }
+			if volume > 130 { volume = 130 }
+			tui.stream_ctl(sel, "volume", volume)
+		case "p":
+			tui.stream_ctl(sel, "play", "yes")
+		case "s":
+			tui.stream_ctl(sel, "play", "no")
+		case "r":
+			tui.stream_ctl(sel, "play", "restart")
+	}
+}
+
+func (tui *Tui) notification(msg []byte) {
+	note := &ClientNote{}
+	if json.Unmarshal(msg, note) != nil { return }
+	var stream *StreamStatus
+	if note.Stream_id != nil { stream = tui.stream(*note.Stream_id) }
+
+	switch note.Notification {
+		case "global_status":
+			status := struct {
+				Playing         bool              `json:"playing"`
+				Streams       []*StreamStatus     `json:"streams"`
+			}{}
+			if json.Unmarshal(note.Payload, &status) != nil { return }
+			if status.Playing != tui.playing {
+				tui.event("streams %s", map[bool]string{true : "started", false : "stopped"}[status.Playing])
+			}
+			tui.playing  = status.Playing
+			tui.streams  = status.Streams
+			if tui.selected >= len(tui.streams) { tui.selected = 0 }
+		case "player_status":
+			status := &PlayerStatus{}
+			if (stream == nil) || (json.Unmarshal(note.Payload, status) != nil) { return }
+			stream.Player_status = status.Status
+			if status.Status == "starting" { stream.Properties = map[string]interface{}{} }
+			line := fmt.Sprintf("[%d] %s", *note.Stream_id, status.Status)
+			if status.Exit_code != nil { line += fmt.Sprintf(" (exit code %d)", *status.Exit_code) }
+			if status.Last_error != "" { line += ": " + status.Last_error }
+			tui.event("%s", line)
+		case "player_event":
+			evt := &PlayerEvent{}
+			if (stream == nil) || (json.Unmarshal(note.Payload, evt) != nil) { return }
+			if evt.Event != "property-change" { return }
+			if stream.Properties == nil { stream.Properties = map[string]interface{}{} }
+			stream.Properties[evt.Name] = evt.Data
+		case "playlist_status", "source_status":
+			status := struct { Location string `json:"location"` }{}
+			if (stream == nil) || (json.Unmarshal(note.Payload, &status) != nil) { return }
+			stream.Location = status.Location
+			tui.event("[%d] %s: %s", *note.Stream_id, strings.TrimSuffix(note.Notification, "_status"), status.Location)
+		case "profile_failed", "schedule_failed", "import_failed", "config_failed", "log_ctl_failed":
+			res := struct { Error string `json:"error"` }{}
+			json.Unmarshal(note.Payload, &res)
+			tui.event("%s: %s", note.Notification, res.Error)
+		case "schedule_fired":
+			tui.event("scheduled action fired")
+	}
+}
+
+func tui_cell(str string, width int) string {
+	runes := []rune(str)
+	if len(runes) > width { runes = runes[:width] }
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+func tui_stream_row(idx int, stream *StreamStatus) string {
+	props := stream.Properties
+	res, vbr, buffer, mute, volume := "-", "-", "-", "-", "-"
+	w, w_ok := props["width"].(float64)
+	h, h_ok := props["height"].(float64)
+	if w_ok && h_ok { res = fmt.Sprintf("%.0fx%.0f", w, h) }
+	if v, ok := props["video-bitrate"].(float64); ok { vbr = fmt.Sprintf("%.1fMb/s", v/1e6) }
+	if v, ok := props["demuxer-cache-duration"].(float64); ok { buffer = fmt.Sprintf("%.1fs", v) }
+	if v, ok := props["mute"].(bool); ok { mute = map[bool]string{true : "yes", false : "no"}[v] }
+	if v, ok := props["volume"].(float64); ok { volume = fmt.Sprintf("%.0f", v) }
+	title := stream.Title
+	if t, ok := props["media-title"].(string); ok && (title == "") { title = t }
+	if title == "" { title = stream.Location }
+	return fmt.Sprintf("%3d  %s %s %s %s %s %s %s", idx,
+		tui_cell(stream.Player_status, 10), tui_cell(res, 10), tui_cell(vbr, 9),
+		tui_cell(buffer, 7), tui_cell(mute, 4), tui_cell(volume, 4), title)
+}
+
+func term_size() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil { return 80, 24 }
+	return width, height
+}
+
+func (tui *Tui) render() {
+	width, height := term_size()
+	tui.width, tui.height, tui.dirty = width, height, false
+	lines := []string{
+		fmt.Sprintf("fnordstream v%s - %d streams", version_info, len(tui.streams)),
+		"",
+		"  #  STATUS     RES        VBR       BUFFER  MUTE VOL  TITLE",
+	}
+	sel_line := -1
+	for idx, stream := range tui.streams {
+		if stream == nil { continue }
+		if idx == tui.selected { sel_line = len(lines) }
+		lines = append(lines, tui_stream_row(idx, stream))
+	}
+	if !tui.playing { lines = append(lines, "     (no streams playing)") }
+	lines = append(lines, "", "Events:")
+
+	footer := "up/down select  m mute  +/- volume  p play  s stop  r restart  M mute all  X stop all  q quit"
+	n_events := height - len(lines) - 2
+	if n_events < 0 { n_events = 0 }
+	events := tui.events
+	if len(events) > n_events { events = events[len(events)-n_events:] }
+	lines = append(lines, events...)
+
+	out := strings.Builder{}
+	out.WriteString("\x1b[H\x1b[2J")
+	for idx, line := range lines {
+		if idx >= height-1 { break }
+		line = tui_cell(line, width)
+		if idx == sel_line { line = "\x1b[7m" + line + "\x1b[0m" }
+		out.WriteString(line + "\r\n")
+	}
+	out.WriteString(fmt.Sprintf("\x1b[%d;1H\x1b[2m%s\x1b[0m", height, tui_cell(footer, width)))
+	tui_term.Lock()
+	defer tui_term.Unlock()
+	if tui_term.restore == nil { return }      // terminal already restored (shutdown signal)
+	os.Stdout.WriteString(out.String())
+}
+
+/* terminal state while the TUI is running - restored by tui_restore()
+ * shutdown_on_signal() calls it as well since it exits w/o returning from tui_run() */
+var tui_term struct {
+	sync.Mutex
+	restore     func()
+}
+
+func tui_restore() {
+	tui_term.Lock()
+	defer tui_term.Unlock()
+	if tui_term.restore == nil { return }
+	tui_term.restore()
+	tui_term.restore = nil
+}
+
+/* run TUI until quit - stops all streams on exit */
+func tui_run(shub *StreamHub, log_lines TuiLog) {
+	fd := int(os.Stdin.Fd())
+	old_state, err := term.MakeRaw(fd)
+	if err != nil {
+		log_fatal(logger("tui"), "cannot switch terminal to raw mode", "err", err)
+	}
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")    // alternate screen, hide cursor
+	tui_term.Lock()
+	tui_term.restore = func() {
+		os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+		term.Restore(fd, old_state)
+	}
+	tui_term.Unlock()
+	defer tui_restore()
+
+	client := &Client{
+		client_request : make(chan map[string]interface{}),
+		client_notify  : make(chan []byte, 256),
+		remote         : "tui",
+	}
+	shub.Register <- client
+	defer func() {
+		shub.Unregister <- client
+		close(client.client_request)
+	}()
+
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil { close(keys); return }
+			keys <- string(buf[:n])
+		}
+	}()
+
+	tui    := &Tui{ client : client }
+	redraw := time.NewTicker(200 * time.Millisecond)    // limit redraws & pick up terminal size changes
+	defer redraw.Stop()
+	tui.request(map[string]interface{}{ "request" : "global_status" })
+	tui.render()
+
+	for !tui.quit {
+		select {
+			case key, ok := <-keys:
+				if !ok { tui.quit = true; break }
+				tui.key(key)
+				tui.render()
+			case msg, ok := <-client.client_notify:
+				if !ok { return }
+				tui.notification(msg)
+				tui.dirty = true
+			case line := <-log_lines:
+				tui.event("%s", line)
+				tui.dirty = true
+			case <-redraw.C:
+				if width, height := term_size(); tui.dirty || (width != tui.width) || (height != tui.height) {
+					tui.render()
+				}
+		}
+	}
+
+	/* stop streams & wait for the hub to confirm */
+	if !tui.playing { return }
+	tui.request(map[string]interface{}{ "request" : "stop_streams" })
+	timeout := time.After(5 * time.Second)
+	for tui.playing {
+		select {
+			case msg, ok := <-client.client_notify:
+				if !ok { return }
+				tui.notification(msg)
+			case <-timeout:
+				return
+		}
+	}
+}
@@ -28,25 +28,48 @@ func (vp *Viewport) String() string {
 
 type PlayerConfig struct {
 	location              string
+	playlist              PlaylistConfig      // location is the current playlist item
 
 	ipc_pipe              string
+	mpv_cmd              *CommandConfig       // player commands (see ServerConfig.command())
 	mpv_args            []string
 
 	use_streamlink        bool
+	streamlink_cmd       *CommandConfig
 	streamlink_args     []string
+	ytdl_cmd             *CommandConfig       // failback probe
 
 	restart_user_quit     bool
 	restart_error_delay   time.Duration
+
+	watchdog              WatchdogConfig
+
+	record_dir            string              // recordings (see recording.go)
+	record_template       string
+
+	thumbnail_file        string              // snapshot destination (empty: no snapshots)
+	thumbnail_interval    time.Duration       // periodic snapshots (0: on request only)
+}
+
+/* stall detection for players with a live IPC connection */
+type WatchdogConfig struct {
+	Stall_timeout       float64   `json:"stall_timeout" mapstructure:"stall_timeout"`  // seconds w/o demuxer-cache-duration update (0: off)
+	Cache_timeout       float64   `json:"cache_timeout" mapstructure:"cache_timeout"`  // max. seconds in paused-for-cache (0: off)
+	Restart             bool      `json:"restart" mapstructure:"restart"`              // restart stalled players
 }
 
 type PlayerStatus struct {
 	Status              string    `json:"status"`
 	Exit_code           *int      `json:"exit_code,omitempty"`
 	Error               string    `json:"error,omitempty"`
+	Last_error          string    `json:"last_error,omitempty"`   // last stderr line of player
 }
 
 type PlayerEvent struct {
 	Event               string         `json:"event" mapstructure:"event"`
+	Id                  int            `json:"id,omitempty" mapstructure:"id"`          // observe id (property-change)
+	Request_id          int            `json:"request_id,omitempty" mapstructure:"request_id"`  // command reply
+	Error               string         `json:"error,omitempty" mapstructure:"error"`            // command reply
 	Name                string         `json:"name" mapstructure:"name"`
 	Data                interface{}    `json:"data" mapstructure:"data"`
 }
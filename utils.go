@@ -1,56 +1,106 @@
 package main
 
 import (
-	"log"
+	"os"
 	"strings"
-	"io/ioutil"
+	"log/slog"
+	"path/filepath"
 	"encoding/json"
-
-	"github.com/go-cmd/cmd"
 )
 
-func load_json(fname string, dst *map[string]interface{}) {
-	content, err := ioutil.ReadFile(fname)
-    if err != nil {
-        log.Println("Cannot read JSON file ", fname,err)
-        return
-    }
-    err = json.Unmarshal(content, dst)
-    if err != nil {
-        log.Fatal("Error during Unmarshal(): ", err)
-    }
+var config_dir string    // -config-dir (overrides per-user config directory)
+
+/* per-user config directory: $XDG_CONFIG_HOME/fnordstream (~/.config/fnordstream),
+ * %AppData%\fnordstream on windows - working directory if unknown */
+func config_path(name string) string {
+	if config_dir != "" { return filepath.Join(config_dir, name) }
+	dir, err := os.UserConfigDir()
+	if err != nil { return name }
+	return filepath.Join(dir, "fnordstream", name)
+}
+
+/* file to load: config dir first, then working directory (location used by older versions)
+ * - no fallback if the config dir was set explicitly */
+func config_load_path(name string) string {
+	fname := config_path(name)
+	if _, err := os.Stat(fname); (err == nil) || (config_dir != "") { return fname }
+	if _, err := os.Stat(name); err == nil { return name }
+	return fname
+}
+
+func load_json(fname string, dst interface{}) error {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		slog.Warn("Cannot read JSON file", "file", fname, "err", err)
+		return err
+	}
+	err = json.Unmarshal(content, dst)
+	if err != nil {
+		slog.Error("Invalid JSON file", "file", fname, "err", err)
+	}
+	return err
 }
 
-func save_json(fname string, src map[string]interface{}) {
-	json, err := json.MarshalIndent(src, "", " ")
+/* atomic save: write temp file in target directory, then rename */
+func save_json(fname string, src interface{}) error {
+	data, err := json.MarshalIndent(src, "", " ")
+	if err != nil {
+		slog.Error("save_json: JSON Marshal error", "file", fname, "err", err)
+		return err
+	}
+	dir := filepath.Dir(fname)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		slog.Error("save_json: cannot create directory", "dir", dir, "err", err)
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(fname)+".tmp*")
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("save_json: cannot create temp file", "file", fname, "err", err)
+		return err
 	}
-	//fmt.Println(string(json))
-	err = ioutil.WriteFile(fname, json, 0644)
+	_, err = tmp.Write(data)
+	if err == nil { err = tmp.Sync() }
+	if cerr := tmp.Close(); err == nil { err = cerr }
+	if err == nil { err = os.Chmod(tmp.Name(), 0644) }
+	if err == nil { err = os.Rename(tmp.Name(), fname) }
 	if err != nil {
-		log.Println(err)
+		os.Remove(tmp.Name())
+		slog.Error("save_json: write failed", "file", fname, "err", err)
 	}
+	return err
 }
 
 type CmdInfo struct {
-	ExitCode   int     `json:"exit_code" mapstructure:"exit_code"`
-	Stdout     string  `json:"stdout,omitempty" mapstructure:"stdout"`
-	Error      string  `json:"error,omitempty" mapstructure:"error"`
+	Path       string       `json:"path,omitempty" mapstructure:"path"`    // resolved path
+	ExitCode   int          `json:"exit_code" mapstructure:"exit_code"`
+	Stdout     string       `json:"stdout,omitempty" mapstructure:"stdout"`
+	Error      string       `json:"error,omitempty" mapstructure:"error"`
+	Version    string       `json:"version,omitempty" mapstructure:"version"`
+	Checks   []*CapCheck    `json:"checks,omitempty" mapstructure:"checks"`    // see capabilities.go
 }
 
-func probe_command(command string) *CmdInfo {
-	ctx    := cmd.NewCmd(command, "--version")
-	status := <-ctx.Start()
+func probe_command(name string, command *CommandConfig) *CmdInfo {
+	path, err := command.resolve()
+	if err != nil {
+		return &CmdInfo{ ExitCode : -1, Error : err.Error() }
+	}
+	status := probe_run(command, "--version")
 
 	res := &CmdInfo{
+		Path     : path,
 		ExitCode : status.Exit,
 	}
 	if status.Error != nil {
 		res.Error = status.Error.Error()
 	}
 	if len(status.Stdout) > 0 {
-		res.Stdout = strings.Join(status.Stdout, "\n")
+		res.Stdout  = strings.Join(status.Stdout, "\n")
+		res.Version = parse_version(res.Stdout)
+	}
+	if checks, ok := probe_checks[name]; ok && (res.ExitCode == 0) {
+		for _, check := range checks(command, res) {
+			if check != nil { res.Checks = append(res.Checks, check) }
+		}
 	}
-    return res
+	return res
 }
@@ -29,6 +29,7 @@ let global           = {            // assembled data from individual fnordstrea
 	},
 
 	cmds_modal      : undefined,    // fnordstream instance for cmds_modal - undefined if hidden
+	log_modal       : undefined,    // [fnordstream, stream_id] for log_modal - undefined if hidden
 
 	ws_send         : ws_send,
 	streamctl       : global_streamctl,
@@ -125,6 +126,29 @@ function register_handlers() {
 		update_stream_profiles();
 	});
 
+	/* export/import profile as JSON file */
+	const profile_export      = document.getElementById('profile_export');
+	const profile_import      = document.getElementById('profile_import');
+	const profile_import_file = document.getElementById('profile_import_file');
+	profile_export.addEventListener('click', (event) => {
+		primary.ws_send({
+			request       : "profile_export",
+			profile_name  : profile_name.value,
+		});
+	});
+	profile_import.addEventListener('click', (event) => profile_import_file.click());
+	profile_import_file.addEventListener('change', (event) => {
+		const file = event.target.files[0];
+		event.target.value = "";
+		if (!file) return;
+		file.text().then(text => {
+			primary.ws_send({
+				request : "profile_import",
+				data    : JSON.parse(text),
+			});
+		}).catch(err => console.warn("profile import failed:", err));
+	});
+
 	/* save profile */
 	profile_save.addEventListener('click', (event) => {
 		let profile = {
@@ -228,6 +252,7 @@ function register_handlers() {
 			val = "";
 		//console.log(val);
 		profile_delete.disabled = val == "";
+		profile_export.disabled = val == "";
 		profile_name.disabled   = val != "";
 		profile_name.value      = val;
 		profile_save.disabled   = profile_name.value == "";
@@ -245,6 +270,10 @@ function register_handlers() {
 	const cmds_modal_refresh = document.getElementById('commands_refresh');
 	cmds_modal_refresh.addEventListener('click', ev => refresh_cmds(global.cmds_modal));
 
+	// player log modal
+	const log_modal          = document.getElementById('log_modal');
+	log_modal.addEventListener('hide.bs.modal', ev => {global.log_modal = null});
+
 	/* ********* control pane *************** */
 
 	const streams_mute_all    = document.getElementById('streams_mute_all');
@@ -316,7 +345,11 @@ function setup_stream_controls(fnordstream, streams) {
 		let nodes = adapt_nodes([n], ext+i);
 
 		nodes.stream_idx.textContent   = stream.viewport_id != undefined ? stream.viewport_id : i;
-		nodes.stream_title.textContent = url;
+		nodes.stream_title.textContent = stream.title || url;
+		if (stream.title) {                 // label from imported playlist - keep it
+			nodes.stream_title.dataset.label = stream.title;
+			nodes.stream_title.title         = url;
+		}
 		const buffer_info_tt = new bootstrap.Tooltip(nodes.stream_buffer_info);
 		nodes.stream_volume.addEventListener('input', (event) => {
 			const val = event.target.value;
@@ -334,6 +367,7 @@ function setup_stream_controls(fnordstream, streams) {
 		nodes.stream_play.addEventListener('click',	   ev => fnordstream.streamctl(i,"play","yes"));
 		nodes.stream_restart.addEventListener('click', ev => fnordstream.streamctl(i,"play","restart"));
 		nodes.stream_ffwd.addEventListener('click',    ev => fnordstream.streamctl(i,"seek","1"));
+		nodes.stream_log.addEventListener('click',     ev => show_stream_log(fnordstream, i));
 		tbody.appendChild(n);
 		return nodes;
 	}); // foreach stream
@@ -563,7 +597,7 @@ function mpv_property_changed(fnordstream, property, stream_id) {
 		"video-bitrate"                   : "stream_vbr",
 	};
 	const update_funcs = { /* node_name -> update function map */
-		"stream_title"  : (n, v) => n.textContent = v,
+		"stream_title"  : (n, v) => n.textContent = n.dataset.label || v,
 		"stream_buffer" : (n, v) => {
 				let str = Math.round(v*10)/10 + "";
 				str += (str.indexOf(".")>=0) ? "" : ".0";
@@ -646,7 +680,7 @@ function player_status(fnordstream, msg) {
 
 	stream_nodes[stream_id].stream_playing.hidden               = status != "playing";
 	stream_nodes[stream_id].stream_stopped.hidden               = (status != "stopped") && (status != "stopping");
-	stream_nodes[stream_id].stream_starting.hidden              = (status != "starting") && (status != "restarting");
+	stream_nodes[stream_id].stream_starting.hidden              = (status != "starting") && (status != "restarting") && (status != "stalled");
 
 	//console.log("player_status", stream_id, status);
 }
@@ -753,15 +787,31 @@ function update_cmds_table(fnordstream, missing_required) {
 
 		const code     = parseInt(cmd.exit_code);
 		const required = required_commands[cmd_name];
+		const failed   = failed_cmd_checks({[cmd_name] : cmd});
+		let   row_type = (code == 0) ? "table-success" : (required ? "table-danger" : "table-warning");
+		if ((code == 0) && (failed.length > 0))
+			row_type = failed.find(check => check.level == "error") ? "table-danger" : "table-warning";
 
-		n.classList.add( (code == 0) ? "table-success" : (required ? "table-danger" : "table-warning"));
+		n.classList.add(row_type);
 
 		const nodes = adapt_nodes(children, i);
 		nodes.cmd_required.textContent = required ? "required" : "optional";
 		nodes.cmd_cmd.innerHTML        = "<b>" + mklink(cmd_name) + "</b>";
+		if (cmd.path) {    // binary actually used
+			const path_node       = document.createElement("small");
+			path_node.textContent = cmd.path;
+			nodes.cmd_cmd.append(document.createElement("br"), path_node);
+		}
 		nodes.cmd_exitcode.innerHTML   = code ?
 			"<b>" + cmd.exit_code + "&#x2718;</b>" : cmd.exit_code + "&#x2714;";
 		nodes.cmd_output.textContent   = cmd.error ? cmd.error : cmd.stdout;
+		(cmd.checks || []).forEach(check => {
+			const line       = document.createElement("div");
+			line.textContent = (check.ok ? "\u2714 " : "\u2718 ") + check.message + (check.hint ? "\n   " + check.hint : "");
+			if (!check.ok)
+				line.classList.add("fw-bold");
+			nodes.cmd_output.appendChild(line);
+		});
 
 		parent.appendChild(n);
 	}
@@ -770,6 +820,16 @@ function update_cmds_table(fnordstream, missing_required) {
 	req_missing.hidden = !(missing_required.length > 0);
 }
 
+/* failed checks of all commands */
+function failed_cmd_checks(cmds) {
+	let res = [];
+	Object.values(cmds).forEach(cmd => {
+		if (cmd && cmd.checks)
+			res = res.concat(cmd.checks.filter(check => !check.ok));
+	});
+	return res;
+}
+
 // OK
 function update_streamlink_availability() {
 	/* disable/enable use_streamlink switch */
@@ -831,6 +891,9 @@ function commands_probed(fnordstream, msg) {
 	missing_required = missing_required.substring(2);
 	missing_optional = missing_optional.substring(2);
 
+	/* failed capability checks (see capabilities.go) */
+	const failed_checks = failed_cmd_checks(results);
+
 	/* build status note */
 	const cmd_status = document.getElementById('cmd_status');
 	const template   = document.getElementById('cmds_alert-');
@@ -852,9 +915,23 @@ function commands_probed(fnordstream, msg) {
 		else
 			alert_msg += "Optional commands failed: " + missing_optional;
 	}
+	if (failed_checks.length > 0) {
+		const errors = failed_checks.find(check => check.level == "error");
+		if (errors)
+			alert_type = 'danger';
+		else if (alert_type == "success")
+			alert_type = 'warning';
+		if (missing_required.length + missing_optional.length == 0)
+			alert_msg = "";
+	}
 	nodes.cmds_alert.classList.add("alert-"+alert_type);
 	nodes.cmds_alert_host.textContent = "@"+fnordstream.host+":";
 	nodes.cmds_alert_msg.innerHTML    = alert_msg;
+	failed_checks.forEach(check => {
+		const line       = document.createElement("div");
+		line.textContent = check.message + (check.hint ? " - " + check.hint : "");
+		nodes.cmds_alert_msg.appendChild(line);
+	});
 
 	if(!fnordstream.cmds_alert)
 		cmd_status.appendChild(alert);
@@ -901,14 +978,75 @@ function profiles_notification(fnordstream, msg) {
 	update_stream_profiles(profiles);
 }
 
+// exported profile - save as file
+function profile_exported(fnordstream, msg) {
+	const doc = msg.payload;
+	if ((!fnordstream.primary) || (!doc)) return;
+	const blob = new Blob([JSON.stringify(doc, null, 1)], {type : "application/json"});
+	const link = document.createElement("a");
+	link.href     = URL.createObjectURL(blob);
+	link.download = doc.name.replace(/[^a-zA-Z0-9._-]+/g, "_") + ".json";
+	link.click();
+	URL.revokeObjectURL(link.href);
+}
+
+function profile_failed(fnordstream, msg) {
+	const res = msg.payload || {};
+	console.warn("profile " + res.profile_name + ": " + res.error);
+	if (fnordstream.primary)      // drop optimistic local changes
+		fnordstream.ws_send({request : "get_profiles"});
+}
+
+function log_line_str(line) {
+	const ts = new Date(line.ts).toLocaleTimeString();
+	return ts + " [" + line.src + "] " + line.line + "\n";
+}
+
+// request player log and show log modal
+function show_stream_log(fnordstream, stream_id) {
+	global.log_modal = [fnordstream, stream_id];
+	document.getElementById('log_modal_stream').textContent = "#" + stream_id + " @" + fnordstream.host;
+	document.getElementById('log_modal_lines').textContent  = "";
+	fnordstream.ws_send({request : "get_stream_log", stream_id : stream_id});
+	const modal = bootstrap.Modal.getOrCreateInstance("#log_modal");
+	modal.show();
+}
+
+function stream_log(fnordstream, msg) {
+	const [log_fns, log_id] = global.log_modal || [];
+	const log = msg.payload;
+	if ((log_fns != fnordstream) || (!log) || (log.stream_id != log_id))
+		return;
+	document.getElementById('log_modal_lines').textContent =
+		(log.lines || []).reduce((res, line) => res + log_line_str(line), "");
+}
+
+// live player output - append to log modal if open for this stream
+function player_log(fnordstream, msg) {
+	const [log_fns, log_id] = global.log_modal || [];
+	if ((log_fns != fnordstream) || (!msg.payload) || (parseInt(msg.stream_id) != log_id))
+		return;
+	document.getElementById('log_modal_lines').textContent += log_line_str(msg.payload);
+}
+
+// notifications were dropped (connection too slow) - global_status snapshot follows
+function client_resync(fnordstream, msg) {
+	console.warn("fnordstream @" + fnordstream.host + ": resync after dropped notifications", msg.payload);
+}
+
 const ws_handlers = {
 	"global_status"  : global_status,
+	"resync"         : client_resync,
 	"probe_commands" : commands_probed,
 	"profiles"       : profiles_notification,
+	"profile_export" : profile_exported,
+	"profile_failed" : profile_failed,
 	"displays"       : displays_notification,
 	"viewports"      : viewports_notification,
 	"player_event"   : player_event,
 	"player_status"  : player_status,
+	"player_log"     : player_log,
+	"stream_log"     : stream_log,
 };
 
 // OK
@@ -2,12 +2,16 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"time"
+	"errors"
+	"context"
 	"strings"
+	"log/slog"
 	"io/fs"
 	"net"
 	"net/url"
 	"net/http"
+	"sync/atomic"
 	"encoding/json"
 
 	"github.com/gorilla/websocket"
@@ -19,6 +23,12 @@ type WSConfig struct {
 	allowed_origins     map[string]bool
 }
 
+var webif_log *slog.Logger    // set up in webif_run()
+
+/* active access config - replaced on reload_config (see webif_reconfigure()) */
+var ws_config atomic.Pointer[WSConfig]
+var webif_listen_host string
+
 /* StreamHub -> Client */
 func ws_Sender(c *Client, conn *websocket.Conn) {
 	defer func() {
@@ -61,7 +71,7 @@ func ws_Receiver(c *Client, conn *websocket.Conn) {
 		c.shub.Unregister <- c
 		close(c.client_request)
 		conn.Close()
-		fmt.Println("ws closed")
+		webif_log.Info("ws closed")
 	}()
 
 	for {
@@ -72,7 +82,7 @@ func ws_Receiver(c *Client, conn *websocket.Conn) {
 		for decoder.More() {
 			var msg map[string]interface{}
 			if err := decoder.Decode(&msg); err != nil {
-				fmt.Println("ws_Receiver JSON decoder:", err)
+				webif_log.Warn("ws_Receiver JSON decoder", "err", err)
 				break
 			}
 
@@ -86,28 +96,23 @@ func ws_Receiver(c *Client, conn *websocket.Conn) {
 var upgrader = websocket.Upgrader{} // use default options
 
 /* start new websock connection */
-func serveWs(shub *StreamHub, w http.ResponseWriter, r *http.Request, cfg *WSConfig) {
+func serveWs(shub *StreamHub, w http.ResponseWriter, r *http.Request) {
 
-	if !auth_check(w, r, cfg.acl) {
+	if !auth_check(w, r, ws_config.Load().acl) {
 		return
 	}
 
-	if cfg.allowed_origins != nil {
-		upgrader.CheckOrigin = func(req *http.Request) bool {
-			return origin_check(req, cfg.allowed_origins)
-		}
-	}
-
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		fmt.Println("upgrade:", err)
+		webif_log.Warn("upgrade failed", "err", err)
 		return
 	}
 
-	fmt.Println("ws started for " + r.RemoteAddr)
+	webif_log.Info("ws started", "remote", r.RemoteAddr)
 
 	client := &Client{
 		shub           : shub,
+		remote         : r.RemoteAddr,
 
 		/* client_notify is written to and closed in StreamHub.Run()
 		 * StreamHub will close client_notify after Client sent Unregister */
@@ -135,7 +140,7 @@ func origin_check(req *http.Request, allowed_origins map[string]bool) bool {
 	u, err := url.Parse(origin[0])
 	if err != nil { return false }
 	allowed := (u.Host == req.Host) || allowed_origins[u.Host]
-	if !allowed { log.Println("Origin",u.Host,"not allowed") }
+	if !allowed { webif_log.Warn("Origin not allowed", "origin", u.Host) }
 	return allowed
 }
 
@@ -146,57 +151,48 @@ func auth_check(w http.ResponseWriter, req *http.Request, acl iprange.Pool) bool
 	ip, _, _ := net.SplitHostPort(req.RemoteAddr)
 	allowed  := acl.Contains(net.ParseIP(ip))
 	if !allowed {
-		log.Println("client",ip,"not authorized in whitelist")
+		webif_log.Warn("client not authorized in whitelist", "ip", ip)
 		returnCode403(w, req)
 	}
 	return allowed
 }
 
-func auth_wrap(h http.Handler, cfg *WSConfig) http.Handler {
+func auth_wrap(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if auth_check(w, req, cfg.acl) {
+		if auth_check(w, req, ws_config.Load().acl) {
 			h.ServeHTTP(w, req)
 		}
   })
 }
 
-func webif_run(shub *StreamHub, listen_spec string, webui_acl string, allowed_origins string) {
+/* build access config from allowed-ips/allowed-origins settings */
+func ws_config_new(listen_host string, webui_acl string, allowed_origins string) (*WSConfig, error) {
 	cfg := &WSConfig{}   //acl iprange.Pool  default: nil (ALLOW ALL)
-
-	log.SetFlags(0)
-
-	fmt.Println("===== webui mode =====")
-
-	// parse listen address
-	listen_host, listen_port, err := net.SplitHostPort(listen_spec)
-	if err != nil {
-		log.Fatal("ERROR: invalid listen ", err)
-	}
-	listen_addr := listen_host + ":" + listen_port
-	fmt.Println("listen address :", listen_addr)
+	log := webif_log
 
 	/* parse client whitelist (if given)
 	   if no client whitelist is provided *ALL* clients will be allowed! */
 	if webui_acl != "<ANY>" {
+		var err error
 		ranges := strings.ReplaceAll(webui_acl, ",", " ")
 		cfg.acl, err = iprange.ParseRanges(ranges)
 		if err != nil {
-			log.Fatal("ERROR: ", err)
+			return nil, fmt.Errorf("invalid allowed-ips: %w", err)
 		}
 		if cfg.acl == nil { // make empty string result in empty range instead of nil
 			cfg.acl = []iprange.Range{}
-			fmt.Println("allowed clients:", "*NONE*", "- very nobody - many blocked - wow!")
+			log.Warn("allowed clients: *NONE* - very nobody - many blocked - wow!")
 		} else {
-			fmt.Println("allowed clients:", cfg.acl)
+			log.Info("allowed clients", "acl", fmt.Sprint(cfg.acl))
 		}
 	}
 
 	// smack user if they attempt to start non-localhost server without restricting access through -allowed-ips
 	if (listen_host != "127.0.0.1") && (listen_host != "localhost") && (cfg.acl == nil) {
-		fmt.Println("allowed clients:", "*ANY*")
-		str := "ERROR: I'm sorry Dave, I'm afraid I can't do that.\n"
-		str += "       For a non-localhost listen address you *MUST* provide a list of allowed clients with -allowed-ips."
-		log.Fatal(str)
+		log.Info("allowed clients: *ANY*")
+		str := "I'm sorry Dave, I'm afraid I can't do that. "
+		str += "For a non-localhost listen address you *MUST* provide a list of allowed clients with -allowed-ips."
+		return nil, errors.New(str)
 	}
 
 	// assemble map of allowed Origins
@@ -206,30 +202,88 @@ func webif_run(shub *StreamHub, listen_spec string, webui_acl string, allowed_or
 		for _, v := range list {
 			if v != "" { cfg.allowed_origins[v] = true }
 		}
-		fmt.Println("allowed origins:",list)
+		log.Info("allowed origins", "origins", list)
+	}
+	return cfg, nil
+}
+
+/* apply allowed-ips/allowed-origins of a reloaded config (no-op w/o web UI) */
+func webif_reconfigure(config *ServerConfig) error {
+	if ws_config.Load() == nil { return nil }
+	cfg, err := ws_config_new(webif_listen_host, config.Allowed_ips, config.Allowed_origins)
+	if err != nil { return err }
+	ws_config.Store(cfg)
+	return nil
+}
+
+/* run web UI until stop is closed - HTTP server is shut down gracefully then
+ * web_dir is served if there is no embedded webfs */
+func webif_run(shub *StreamHub, listen_spec string, webui_acl string, allowed_origins string, web_dir string, stop <-chan struct{}) {
+	webif_log = logger("webif")
+	log      := webif_log
+	log.Info("===== webui mode =====")
+
+	// parse listen address
+	listen_host, listen_port, err := net.SplitHostPort(listen_spec)
+	if err != nil {
+		log_fatal(log, "invalid listen address", "err", err)
+	}
+	listen_addr := listen_host + ":" + listen_port
+	log.Info("listen address", "addr", listen_addr)
+
+	cfg, err := ws_config_new(listen_host, webui_acl, allowed_origins)
+	if err != nil {
+		log_fatal(log, err.Error())
+	}
+	webif_listen_host = listen_host
+	ws_config.Store(cfg)
+	upgrader.CheckOrigin = func(req *http.Request) bool {
+		return origin_check(req, ws_config.Load().allowed_origins)
 	}
 
 	// serve embedded webfs or web/ directory?
 	var web_fs http.FileSystem
 	if(embedded_webfs_valid) {
-		fmt.Println("serving embedded webfs")
+		log.Info("serving embedded webfs")
 		fsys       := fs.FS(embedded_webfs)
 		webdir, _  := fs.Sub(fsys, "web")
 		web_fs      = http.FS(webdir)
 	} else {
-		fmt.Println("serving web/ directory")
-		web_fs      = http.Dir("./web")
+		log.Info("serving web directory", "dir", web_dir)
+		web_fs      = http.Dir(web_dir)
 	}
 
-	http.Handle("/", auth_wrap(http.FileServer(web_fs), cfg))
+	http.Handle("/", auth_wrap(http.FileServer(web_fs)))
+	http.Handle("/metrics", auth_wrap(metrics_handler(shub)))
+	if shub.thumbnail_dir != "" {
+		http.Handle("/streams/", auth_wrap(thumbnail_handler(shub.thumbnail_dir)))
+	}
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		serveWs(shub, w, r, cfg)   // websocket
+		serveWs(shub, w, r)   // websocket
 	})
 
+	listener, err := net.Listen("tcp", listen_addr)
+	if err != nil {
+		log_fatal(log, "cannot listen", "err", err)
+	}
 	fmt.Println("open this link in your browser: http://localhost:"+listen_port)
+	service_ready(shub)
+
+	srv  := &http.Server{ Addr : listen_addr }
+	done := make(chan struct{})
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Warn("HTTP server shutdown", "err", err)
+		}
+		close(done)
+	}()
 
-	err = http.ListenAndServe(listen_addr, nil)
-	if err != nil {
-		log.Fatal("ListenAndServe: ", err)
+	err = srv.Serve(listener)
+	if err != http.ErrServerClosed {
+		log_fatal(log, "Serve failed", "err", err)
 	}
+	<-done
 }